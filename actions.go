@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gizak/termui"
+)
+
+// toastDuration is how long the status of an action is shown for.
+const toastDuration = 5 * time.Second
+
+// Actioner is implemented by selectable data sources with actions that can
+// be run on the selected item.
+type Actioner interface {
+	// Actions returns the actions for the item with the key.
+	Actions(data interface{}, key string) []action
+}
+
+// action describes something that can be done to a selected item, ie.
+// rebuilding a job.
+type action struct {
+	// key is the key that starts the action.
+	key string
+	// name describes the action, ie. "rebuild app".
+	name string
+	// form returns the fields to fill in before running the action.
+	// It is nil if the action has no form.
+	form func(ctx context.Context) ([]field, error)
+	// run does the action with the values of the fields from the form
+	// that were changed. The values are nil if the action has no form.
+	run func(ctx context.Context, values map[string]string) error
+}
+
+// field describes a field in the form for an action.
+type field struct {
+	name        string
+	description string
+	value       string
+	// choices holds the values the field can have, if it is limited to
+	// them.
+	choices []string
+	// edited is set once the value has been changed in the form.
+	edited bool
+}
+
+// prompt holds the form or confirmation being shown for an action.
+// While it is open, it gets all the key presses.
+var prompt struct {
+	sync.Mutex
+	action  *action
+	fields  []field
+	field   int
+	confirm bool
+}
+
+// toast holds the status of the last action run.
+var toast struct {
+	sync.Mutex
+	text  string
+	color termui.Attribute
+	until time.Time
+}
+
+// startAction starts the action for the key on the selected item. If the
+// action has a form, it is opened, otherwise the action must be confirmed
+// before it is run. It returns false if the selected item has no action for
+// the key.
+func startAction(ctx context.Context, key string, render func()) bool {
+	selection.Lock()
+	s, selected := selection.source, selection.key
+	selection.Unlock()
+
+	if s == nil {
+		return false
+	}
+	a, ok := s.Source.(Actioner)
+	if !ok {
+		return false
+	}
+
+	s.mu.Lock()
+	var actions []action
+	if s.data != nil {
+		actions = a.Actions(s.data, selected)
+	}
+	s.mu.Unlock()
+
+	for i := range actions {
+		act := &actions[i]
+		if act.key != key {
+			continue
+		}
+
+		if act.form == nil {
+			openPrompt(act, nil)
+			return true
+		}
+
+		// Get the fields for the form without blocking the key presses.
+		showToast("loading the form to "+act.name+"...", termui.ColorYellow)
+		go func() {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			fields, err := act.form(ctx)
+			if err != nil {
+				showToast(fmt.Sprintf("loading the form to %s failed: %v", act.name, err), termui.ColorRed)
+			} else {
+				clearToast()
+				openPrompt(act, fields)
+			}
+			render()
+		}()
+		return true
+	}
+
+	return false
+}
+
+// openPrompt opens the form for the action, or if it has no fields, asks for
+// confirmation to run it.
+func openPrompt(act *action, fields []field) {
+	prompt.Lock()
+	defer prompt.Unlock()
+
+	prompt.action = act
+	prompt.fields = fields
+	prompt.field = 0
+	prompt.confirm = len(fields) <= 0
+}
+
+// handlePromptKey handles a key press while a form or confirmation is open.
+// It returns false if there is nothing open, so the key press should be
+// handled as usual. It is only called from the goroutine handling the keys,
+// so the text typed into a field stays in order.
+func handlePromptKey(ctx context.Context, key string, render func()) bool {
+	prompt.Lock()
+	defer prompt.Unlock()
+
+	if prompt.action == nil {
+		return false
+	}
+
+	if prompt.confirm {
+		switch key {
+		case "y", "<enter>":
+			go runAction(ctx, *prompt.action, formValues(prompt.fields), render)
+			prompt.action = nil
+			prompt.fields = nil
+		case "n", "<escape>", "q":
+			prompt.action = nil
+			prompt.fields = nil
+		}
+		return true
+	}
+
+	f := &prompt.fields[prompt.field]
+	switch key {
+	case "<escape>":
+		prompt.action = nil
+		prompt.fields = nil
+	case "<enter>":
+		prompt.confirm = true
+	case "<tab>", "<down>":
+		prompt.field = (prompt.field + 1) % len(prompt.fields)
+	case "<up>":
+		prompt.field = (prompt.field + len(prompt.fields) - 1) % len(prompt.fields)
+	case "<left>", "<right>":
+		f.value = nextChoice(f.choices, f.value, key == "<right>")
+		f.edited = true
+	case "<backspace>", "C-8":
+		if r := []rune(f.value); len(r) > 0 {
+			f.value = string(r[:len(r)-1])
+			f.edited = true
+		}
+	case "C-u":
+		f.value = ""
+		f.edited = true
+	case "<space>":
+		f.value += " "
+		f.edited = true
+	default:
+		// Only add printable characters to the value.
+		if len([]rune(key)) == 1 {
+			f.value += key
+			f.edited = true
+		}
+	}
+	return true
+}
+
+// nextChoice returns the choice after or before value, wrapping around.
+func nextChoice(choices []string, value string, forward bool) string {
+	if len(choices) <= 0 {
+		return value
+	}
+
+	i := -1
+	for j, c := range choices {
+		if c == value {
+			i = j
+			break
+		}
+	}
+	if forward {
+		return choices[(i+1)%len(choices)]
+	}
+	if i <= 0 {
+		return choices[len(choices)-1]
+	}
+	return choices[i-1]
+}
+
+// formValues returns the values of the fields that were edited by their
+// names. The others are left out, so they get their defaults where the
+// action is run rather than the value shown in the form, which for hidden
+// values, ie. passwords, is empty. It returns nil if there are no fields.
+func formValues(fields []field) map[string]string {
+	if len(fields) <= 0 {
+		return nil
+	}
+
+	values := map[string]string{}
+	for _, f := range fields {
+		if f.edited {
+			values[f.name] = f.value
+		}
+	}
+	return values
+}
+
+// runAction runs the action, showing its status as a toast.
+func runAction(ctx context.Context, act action, values map[string]string, render func()) {
+	showToast(act.name+"...", termui.ColorYellow)
+	render()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := act.run(ctx, values); err != nil {
+		showToast(fmt.Sprintf("%s failed: %v", act.name, err), termui.ColorRed)
+	} else {
+		showToast(act.name+" done", termui.ColorGreen)
+	}
+	render()
+
+	// Render again once the toast is gone.
+	time.AfterFunc(toastDuration, render)
+}
+
+// showToast shows the text for toastDuration.
+func showToast(text string, color termui.Attribute) {
+	toast.Lock()
+	defer toast.Unlock()
+
+	toast.text = text
+	toast.color = color
+	toast.until = time.Now().Add(toastDuration)
+}
+
+// clearToast stops showing the toast.
+func clearToast() {
+	toast.Lock()
+	defer toast.Unlock()
+
+	toast.text = ""
+}
+
+// promptRows returns the rows for the open form or confirmation, if any.
+func promptRows() []*termui.Row {
+	prompt.Lock()
+	defer prompt.Unlock()
+
+	if prompt.action == nil {
+		return nil
+	}
+
+	if prompt.confirm {
+		p := termui.NewPar(fmt.Sprintf("%s? [y/n]", capitalize(prompt.action.name)))
+		p.TextFgColor = termui.ColorYellow
+		p.BorderFg = termui.ColorYellow
+		p.BorderLabel = "Confirm"
+		p.Height = 3
+		return []*termui.Row{termui.NewRow(termui.NewCol(12, 0, p))}
+	}
+
+	items := []string{}
+	for i, f := range prompt.fields {
+		cursor := "  "
+		if i == prompt.field {
+			cursor = "> "
+		}
+		item := fmt.Sprintf("%s%s: %s", cursor, f.name, f.value)
+		if i == prompt.field {
+			item += "_"
+			if len(f.description) > 0 {
+				item += "  (" + f.description + ")"
+			}
+		}
+		items = append(items, item)
+	}
+	items = append(items, "", "<tab> next field, <left>/<right> change choice, <enter> submit, <escape> cancel")
+
+	list := termui.NewList()
+	list.Items = items
+	list.ItemFgColor = termui.ColorWhite
+	list.BorderFg = termui.ColorYellow
+	list.BorderLabel = capitalize(prompt.action.name)
+	list.Height = len(items) + 2
+	return []*termui.Row{termui.NewRow(termui.NewCol(12, 0, list))}
+}
+
+// capitalize returns s with its first letter in upper case.
+func capitalize(s string) string {
+	if len(s) <= 0 {
+		return s
+	}
+	r := []rune(s)
+	return strings.ToUpper(string(r[0])) + string(r[1:])
+}
+
+// toastRows returns the rows for the toast, if it is being shown.
+func toastRows() []*termui.Row {
+	toast.Lock()
+	defer toast.Unlock()
+
+	if len(toast.text) <= 0 || time.Now().After(toast.until) {
+		return nil
+	}
+
+	p := termui.NewPar(toast.text)
+	p.TextFgColor = toast.color
+	p.BorderFg = toast.color
+	p.Height = 3
+	return []*termui.Row{termui.NewRow(termui.NewCol(12, 0, p))}
+}
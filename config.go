@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// config describes the dashboard configuration file.
+type config struct {
+	Interval time.Duration   `yaml:"interval,omitempty"`
+	Timeout  time.Duration   `yaml:"timeout,omitempty"`
+	All      *bool           `yaml:"all,omitempty"`
+	HTTP     httpConfig      `yaml:"http,omitempty"`
+	Sources  []*sourceConfig `yaml:"sources,omitempty"`
+	Layout   [][]layoutCell  `yaml:"layout,omitempty"`
+}
+
+// httpConfig describes the settings for the HTTP client shared by all the
+// data sources.
+type httpConfig struct {
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	Retries *int          `yaml:"retries,omitempty"`
+}
+
+// sourceConfig describes a named data source in the configuration file.
+// Any keys other than name, type, interval and timeout are settings specific
+// to the type of the data source.
+type sourceConfig struct {
+	Name     string
+	Type     string
+	Interval time.Duration
+	Timeout  time.Duration
+
+	settings map[string]interface{}
+}
+
+// layoutCell describes a single column in a row of the dashboard layout.
+type layoutCell struct {
+	Source string `yaml:"source"`
+	Span   int    `yaml:"span,omitempty"`
+	Offset int    `yaml:"offset,omitempty"`
+}
+
+// columns returns the number of columns the cell spans in a row with n
+// cells. Cells without a span split the row evenly.
+func (c layoutCell) columns(n int) int {
+	if c.Span > 0 {
+		return c.Span
+	}
+	return 12 / n
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *sourceConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw struct {
+		Name     string                 `yaml:"name"`
+		Type     string                 `yaml:"type"`
+		Interval time.Duration          `yaml:"interval"`
+		Timeout  time.Duration          `yaml:"timeout"`
+		Settings map[string]interface{} `yaml:",inline"`
+	}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	c.Name = raw.Name
+	c.Type = raw.Type
+	c.Interval = raw.Interval
+	c.Timeout = raw.Timeout
+	c.settings = raw.Settings
+
+	return nil
+}
+
+// decode decodes the settings for the data source into v.
+// Unknown settings are returned as an error.
+func (c *sourceConfig) decode(v interface{}) error {
+	if c == nil || len(c.settings) <= 0 {
+		return nil
+	}
+
+	b, err := yaml.Marshal(c.settings)
+	if err != nil {
+		return err
+	}
+
+	if err := yaml.UnmarshalStrict(b, v); err != nil {
+		return fmt.Errorf("decoding settings for %s data source %q failed: %v", c.Type, c.Name, err)
+	}
+
+	return nil
+}
+
+// readConfig reads the configuration file at path.
+// If the file does not exist and it is not required, an empty config
+// is returned.
+func readConfig(path string, required bool) (*config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			return &config{}, nil
+		}
+		return nil, fmt.Errorf("reading config file %q failed: %v", path, err)
+	}
+
+	var c config
+	if err := yaml.UnmarshalStrict(data, &c); err != nil {
+		return nil, fmt.Errorf("parsing config file %q failed: %v", path, err)
+	}
+
+	// Validate the data sources.
+	names := map[string]bool{}
+	for i, s := range c.Sources {
+		if len(s.Type) <= 0 {
+			return nil, fmt.Errorf("data source %d in config file %q is missing a type", i, path)
+		}
+		if len(s.Name) <= 0 {
+			s.Name = s.Type
+		}
+		if names[s.Name] {
+			return nil, fmt.Errorf("data source name %q in config file %q is not unique", s.Name, path)
+		}
+		names[s.Name] = true
+	}
+
+	// Validate the layout.
+	for _, row := range c.Layout {
+		span := 0
+		for _, cell := range row {
+			if !names[cell.Source] {
+				return nil, fmt.Errorf("layout in config file %q references unknown data source %q", path, cell.Source)
+			}
+			if cell.columns(len(row)) <= 0 {
+				return nil, fmt.Errorf("layout row in config file %q has more than 12 cells without a span", path)
+			}
+			span += cell.columns(len(row)) + cell.Offset
+		}
+		if span > 12 {
+			return nil, fmt.Errorf("layout row in config file %q spans more than 12 columns", path)
+		}
+	}
+
+	return &c, nil
+}
+
+// flagPassed returns true if the global flag was passed on the command line.
+// Flags that were passed override values from the configuration file.
+func flagPassed(name string) bool {
+	return passedFlags[name]
+}
+
+// matchPattern returns true if name matches the glob pattern. A * matches
+// anything but a /, ** matches anything including a / and ? matches a single
+// character that is not a /.
+func matchPattern(pattern, name string) bool {
+	var re strings.Builder
+	re.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				re.WriteString(".*")
+				i++
+				continue
+			}
+			re.WriteString("[^/]*")
+		case '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	re.WriteString("$")
+
+	ok, _ := regexp.MatchString(re.String(), name)
+	return ok
+}
+
+// matchPatterns returns true if name should be shown given the include and
+// exclude patterns. If there are no include patterns, everything that is not
+// excluded is shown.
+func matchPatterns(include, exclude []string, name string) bool {
+	for _, p := range exclude {
+		if matchPattern(p, name) {
+			return false
+		}
+	}
+
+	if len(include) <= 0 {
+		return true
+	}
+	for _, p := range include {
+		if matchPattern(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandPath expands a leading ~ in path to the home directory.
+func expandPath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := getHome()
+		if err != nil {
+			return path
+		}
+		return filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+	return path
+}
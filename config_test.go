@@ -0,0 +1,303 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig writes the config to a file in a temporary directory and
+// returns its path.
+func writeConfig(t *testing.T, data string) string {
+	dir, err := ioutil.TempDir("", "tdash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadConfig(t *testing.T) {
+	retries := 5
+
+	testCases := []struct {
+		name    string
+		config  string
+		want    *config
+		wantErr string
+	}{
+		{
+			name: "empty",
+			want: &config{},
+		},
+		{
+			name: "sources and layout",
+			config: `
+interval: 5m
+timeout: 30s
+http:
+  retries: 5
+sources:
+  - name: ci
+    type: jenkins
+    interval: 1m
+    uri: https://jenkins.example.com
+  - type: travis
+    owners: [jessfraz]
+layout:
+  - [{source: ci, span: 8}, {source: travis, span: 4}]
+`,
+			want: &config{
+				Interval: 5 * time.Minute,
+				Timeout:  30 * time.Second,
+				HTTP:     httpConfig{Retries: &retries},
+				Sources: []*sourceConfig{
+					{
+						Name:     "ci",
+						Type:     "jenkins",
+						Interval: time.Minute,
+						settings: map[string]interface{}{"uri": "https://jenkins.example.com"},
+					},
+					{
+						// The name defaults to the type.
+						Name:     "travis",
+						Type:     "travis",
+						settings: map[string]interface{}{"owners": []interface{}{"jessfraz"}},
+					},
+				},
+				Layout: [][]layoutCell{{{Source: "ci", Span: 8}, {Source: "travis", Span: 4}}},
+			},
+		},
+		{
+			name:    "unknown key",
+			config:  "refresh: 5m\n",
+			wantErr: "field refresh not found",
+		},
+		{
+			name:    "missing type",
+			config:  "sources:\n  - name: ci\n",
+			wantErr: "data source 0 in config file",
+		},
+		{
+			name:    "duplicate names",
+			config:  "sources:\n  - type: travis\n  - name: travis\n    type: jenkins\n",
+			wantErr: `data source name "travis"`,
+		},
+		{
+			name:    "unknown source in layout",
+			config:  "sources:\n  - type: travis\nlayout:\n  - [{source: jenkins}]\n",
+			wantErr: `unknown data source "jenkins"`,
+		},
+		{
+			name:    "layout row too wide",
+			config:  "sources:\n  - type: travis\n  - type: jenkins\nlayout:\n  - [{source: travis, span: 6, offset: 1}, {source: jenkins, span: 6}]\n",
+			wantErr: "spans more than 12 columns",
+		},
+		{
+			name:    "layout row too wide without spans",
+			config:  "sources:\n  - type: travis\n  - type: jenkins\nlayout:\n  - [{source: travis, span: 10}, {source: jenkins}]\n",
+			wantErr: "spans more than 12 columns",
+		},
+		{
+			name:    "layout row with too many cells",
+			config:  "sources:\n  - type: travis\nlayout:\n  - [" + strings.Repeat("{source: travis}, ", 12) + "{source: travis}]\n",
+			wantErr: "more than 12 cells without a span",
+		},
+		{
+			name:   "layout row just fits",
+			config: "sources:\n  - type: travis\n  - type: jenkins\nlayout:\n  - [{source: travis, span: 5, offset: 1}, {source: jenkins, span: 6}]\n",
+			want: &config{
+				Sources: []*sourceConfig{
+					{Name: "travis", Type: "travis"},
+					{Name: "jenkins", Type: "jenkins"},
+				},
+				Layout: [][]layoutCell{{{Source: "travis", Span: 5, Offset: 1}, {Source: "jenkins", Span: 6}}},
+			},
+		},
+		{
+			name:   "layout row split evenly",
+			config: "sources:\n  - type: travis\n  - type: jenkins\nlayout:\n  - [{source: travis, offset: 1}, {source: jenkins, span: 5}]\n",
+			want: &config{
+				Sources: []*sourceConfig{
+					{Name: "travis", Type: "travis"},
+					{Name: "jenkins", Type: "jenkins"},
+				},
+				Layout: [][]layoutCell{{{Source: "travis", Offset: 1}, {Source: "jenkins", Span: 5}}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := readConfig(writeConfig(t, tc.config), true)
+			if len(tc.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadConfigMissing(t *testing.T) {
+	path := filepath.Join(os.TempDir(), "tdash-does-not-exist.yaml")
+
+	c, err := readConfig(path, false)
+	if err != nil {
+		t.Fatalf("a missing config file that is not required failed: %v", err)
+	}
+	if !reflect.DeepEqual(c, &config{}) {
+		t.Errorf("got %#v, want an empty config", c)
+	}
+
+	if _, err := readConfig(path, true); err == nil {
+		t.Error("a missing config file that is required did not fail")
+	}
+}
+
+func TestSourceConfigDecode(t *testing.T) {
+	type settings struct {
+		URI    string   `yaml:"uri"`
+		Owners []string `yaml:"owners"`
+		Top    int      `yaml:"top"`
+	}
+
+	testCases := []struct {
+		name    string
+		cfg     *sourceConfig
+		want    settings
+		wantErr string
+	}{
+		{
+			name: "nil",
+			want: settings{Top: 5},
+		},
+		{
+			name: "no settings",
+			cfg:  &sourceConfig{Name: "ci", Type: "jenkins"},
+			want: settings{Top: 5},
+		},
+		{
+			name: "settings",
+			cfg: &sourceConfig{Name: "ci", Type: "jenkins", settings: map[string]interface{}{
+				"uri":    "https://jenkins.example.com",
+				"owners": []interface{}{"jessfraz"},
+			}},
+			want: settings{URI: "https://jenkins.example.com", Owners: []string{"jessfraz"}, Top: 5},
+		},
+		{
+			name: "unknown setting",
+			cfg: &sourceConfig{Name: "ci", Type: "jenkins", settings: map[string]interface{}{
+				"url": "https://jenkins.example.com",
+			}},
+			wantErr: `decoding settings for jenkins data source "ci" failed`,
+		},
+		{
+			name: "wrong type",
+			cfg: &sourceConfig{Name: "ci", Type: "jenkins", settings: map[string]interface{}{
+				"top": "five",
+			}},
+			wantErr: "cannot unmarshal",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Decoding keeps the defaults that are not set.
+			got := settings{Top: 5}
+			err := tc.cfg.decode(&got)
+			if len(tc.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "tdash", name: "tdash", want: true},
+		{pattern: "tdash", name: "tdash2"},
+		{pattern: "tdash", name: "TDash"},
+		{pattern: "", name: "", want: true},
+		{pattern: "*", name: "tdash", want: true},
+		{pattern: "*", name: "", want: true},
+		{pattern: "t*", name: "tdash", want: true},
+		{pattern: "*sh", name: "tdash", want: true},
+		{pattern: "*-test", name: "tdash"},
+		{pattern: "*", name: "folder/job"},
+		{pattern: "folder/*", name: "folder/job", want: true},
+		{pattern: "folder/*", name: "folder/sub/job"},
+		{pattern: "folder/**", name: "folder/sub/job", want: true},
+		{pattern: "**", name: "folder/sub/job", want: true},
+		{pattern: "**/job", name: "folder/sub/job", want: true},
+		{pattern: "t?ash", name: "tdash", want: true},
+		{pattern: "t?ash", name: "tash"},
+		{pattern: "a?b", name: "a/b"},
+		{pattern: "go.*", name: "go.mod", want: true},
+		{pattern: "go.*", name: "gosum"},
+		{pattern: "[a]+", name: "[a]+", want: true},
+		{pattern: "[a]+", name: "aa"},
+	}
+
+	for _, tc := range testCases {
+		if got := matchPattern(tc.pattern, tc.name); got != tc.want {
+			t.Errorf("matchPattern(%q, %q): got %t, want %t", tc.pattern, tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestMatchPatterns(t *testing.T) {
+	testCases := []struct {
+		name    string
+		include []string
+		exclude []string
+		repo    string
+		want    bool
+	}{
+		{name: "no patterns", repo: "tdash", want: true},
+		{name: "included", include: []string{"t*", "go-*"}, repo: "tdash", want: true},
+		{name: "not included", include: []string{"go-*"}, repo: "tdash"},
+		{name: "excluded", exclude: []string{"*-test"}, repo: "tdash-test"},
+		{name: "not excluded", exclude: []string{"*-test"}, repo: "tdash", want: true},
+		{name: "exclude wins", include: []string{"tdash*"}, exclude: []string{"*-test"}, repo: "tdash-test"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := matchPatterns(tc.include, tc.exclude, tc.repo); got != tc.want {
+				t.Errorf("got %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -9,46 +10,94 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+func init() {
+	registerSource("googleanalytics", func() Source { return &gaSource{} })
+}
+
 type gaData struct {
 	name        string
 	table       *termui.Table
 	activeUsers string
 }
 
-func doGoogleAnalytics() ([]gaData, error) {
-	if _, err := os.Stat(googleAnalyticsKeyfile); os.IsNotExist(err) {
-		logrus.Warnf("Google Analytics keyfile %q does not exist", googleAnalyticsKeyfile)
-		logrus.Info("skipping Google Analytics data")
-		return nil, nil
+// gaSettings holds the settings for a Google Analytics data source.
+type gaSettings struct {
+	Keyfile string   `yaml:"keyfile"`
+	ViewIDs []string `yaml:"viewids"`
+}
+
+// gaSource is a Source for Google Analytics data.
+type gaSource struct {
+	settings gaSettings
+	client   *googleanalytics.Client
+}
+
+// Name implements Source.
+func (s *gaSource) Name() string {
+	return "Google Analytics"
+}
+
+// Configure implements Source.
+func (s *gaSource) Configure(cfg *sourceConfig) (bool, error) {
+	// Get the settings from the flags and config file.
+	s.settings = gaSettings{
+		Keyfile: googleAnalyticsKeyfile,
+		ViewIDs: googleAnalyticsViewIDs,
+	}
+	if err := cfg.decode(&s.settings); err != nil {
+		return false, err
+	}
+	if flagPassed("ga-keyfile") {
+		s.settings.Keyfile = googleAnalyticsKeyfile
+	}
+	if flagPassed("ga-viewid") {
+		s.settings.ViewIDs = googleAnalyticsViewIDs
+	}
+	s.settings.Keyfile = expandPath(s.settings.Keyfile)
+
+	if _, err := os.Stat(s.settings.Keyfile); os.IsNotExist(err) {
+		logrus.Warnf("Google Analytics keyfile %q does not exist", s.settings.Keyfile)
+		return false, nil
 	}
 
 	// Check that the Google Analytics view ID is not empty.
-	if len(googleAnalyticsViewIDs) <= 0 {
+	if len(s.settings.ViewIDs) <= 0 {
 		logrus.Warn("Google Analytics view ID cannot be empty")
-		logrus.Info("skipping Google Analytics data")
-		return nil, nil
+		return false, nil
 	}
 
 	// Create the Google Analytics Client
-	gaClient, err := googleanalytics.New(googleAnalyticsKeyfile, debug)
+	var err error
+	s.client, err = googleanalytics.New(s.settings.Keyfile, httpClient)
 	if err != nil {
-		return nil, fmt.Errorf("creating Google Analytics client failed: %v", err)
+		return false, fmt.Errorf("creating Google Analytics client failed: %v", err)
 	}
 
+	return true, nil
+}
+
+// Fetch implements Source.
+func (s *gaSource) Fetch(ctx context.Context) (interface{}, error) {
 	// Iterate over the Google Analytics view IDs.
 	data := []gaData{}
-	for _, gaViewID := range googleAnalyticsViewIDs {
+	for _, gaViewID := range s.settings.ViewIDs {
+		// Return early if we ran out of time.
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		// Initialize our gaData.
 		ga := gaData{}
 
 		// Get the name of our Google Analytics view ID.
-		ga.name, err = gaClient.GetProfileName(gaViewID)
+		var err error
+		ga.name, err = s.client.GetProfileName(gaViewID)
 		if err != nil {
 			return nil, fmt.Errorf("getting Google Analytics view name for %q failed: %v", gaViewID, err)
 		}
 
 		// Get the Google Analytics report.
-		resp, err := gaClient.GetReport(gaViewID)
+		resp, err := s.client.GetReport(gaViewID)
 		if err != nil {
 			return nil, fmt.Errorf("getting Google Analytics report for view %q failed: %v", gaViewID, err)
 		}
@@ -61,7 +110,7 @@ func doGoogleAnalytics() ([]gaData, error) {
 		}
 
 		// Get the realtime data for users.
-		ga.activeUsers, err = gaClient.GetRealtimeActiveUsers(gaViewID)
+		ga.activeUsers, err = s.client.GetRealtimeActiveUsers(gaViewID)
 		if err != nil {
 			return nil, fmt.Errorf("getting Google Analytics realtime active users data for view %q failed: %v", gaViewID, err)
 		}
@@ -72,3 +121,29 @@ func doGoogleAnalytics() ([]gaData, error) {
 
 	return data, nil
 }
+
+// Render implements Source.
+func (s *gaSource) Render(v interface{}) []*termui.Row {
+	rows := []*termui.Row{}
+
+	// Add Google Analytics data to the termui body.
+	for _, data := range v.([]gaData) {
+		if data.table == nil {
+			continue
+		}
+
+		data.table.Block.BorderLabel = "Google Analytics data for " + data.name
+
+		activeUsers := termui.NewPar(data.activeUsers)
+		activeUsers.TextFgColor = termui.ColorWhite
+		activeUsers.BorderFg = termui.ColorWhite
+		activeUsers.BorderLabel = "Active users for " + data.name
+		activeUsers.Height = 3
+
+		rows = append(rows,
+			termui.NewRow(termui.NewCol(9, 0, data.table), termui.NewCol(3, 0, activeUsers)),
+		)
+	}
+
+	return rows
+}
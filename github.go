@@ -0,0 +1,409 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+)
+
+// githubOwner holds the settings for an owner of GitHub repositories.
+// In the config file it is either the name of the owner or a mapping.
+type githubOwner struct {
+	Name string `yaml:"name"`
+	// Include and Exclude are patterns for the names of the repositories
+	// of the owner to show, as for the jobs of a Jenkins data source.
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (o *githubOwner) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&o.Name); err == nil {
+		return nil
+	}
+
+	type plain githubOwner
+	return unmarshal((*plain)(o))
+}
+
+// githubOwnersFromFlags returns the owners passed on the command line.
+func githubOwnersFromFlags() []githubOwner {
+	owners := []githubOwner{}
+	for _, name := range githubOwners {
+		owners = append(owners, githubOwner{Name: name})
+	}
+	return owners
+}
+
+// validateGitHubOwners checks that each of the owners has a name.
+func validateGitHubOwners(owners []githubOwner) error {
+	for i, owner := range owners {
+		if len(owner.Name) <= 0 {
+			return fmt.Errorf("github owner %d is missing a name", i)
+		}
+	}
+	return nil
+}
+
+// githubSettings holds the settings shared by the GitHub data sources.
+type githubSettings struct {
+	Owners []githubOwner `yaml:"owners"`
+
+	githubAPI `yaml:",inline"`
+}
+
+// githubRepoSettings holds the settings shared by the GitHub data sources
+// that get data for each repository of the owners.
+type githubRepoSettings struct {
+	githubSettings `yaml:",inline"`
+
+	// Concurrency is the number of repositories to get the data for at
+	// once.
+	Concurrency int `yaml:"concurrency"`
+}
+
+// githubSettingsFromFlags returns the GitHub settings passed on the command
+// line.
+func githubSettingsFromFlags() githubSettings {
+	return githubSettings{
+		Owners:    githubOwnersFromFlags(),
+		githubAPI: githubAPIFromFlags(),
+	}
+}
+
+// githubRepoSettingsFromFlags returns the GitHub settings passed on the
+// command line, with the default concurrency.
+func githubRepoSettingsFromFlags() githubRepoSettings {
+	return githubRepoSettings{
+		githubSettings: githubSettingsFromFlags(),
+		Concurrency:    8,
+	}
+}
+
+// configure overrides the settings from the config file with the flags that
+// were passed on the command line, validates them and returns a client for
+// the API. It returns false if there are no owners, so the data source
+// should be skipped.
+func (g *githubSettings) configure() (*github.Client, bool, error) {
+	if flagPassed("github-owner") {
+		g.Owners = githubOwnersFromFlags()
+	}
+	g.overrideFlags()
+
+	// Check that the GitHub owners is not empty.
+	if len(g.Owners) <= 0 {
+		logrus.Warn("GitHub owners cannot be empty")
+		return nil, false, nil
+	}
+	if err := validateGitHubOwners(g.Owners); err != nil {
+		return nil, false, err
+	}
+
+	client, err := g.newClient()
+	if err != nil {
+		return nil, false, err
+	}
+	return client, true, nil
+}
+
+// configure does the same as githubSettings.configure, making sure the
+// concurrency is at least one.
+func (g *githubRepoSettings) configure() (*github.Client, bool, error) {
+	if g.Concurrency <= 0 {
+		g.Concurrency = 1
+	}
+	return g.githubSettings.configure()
+}
+
+// githubAPI holds the settings for the GitHub API shared by the GitHub data
+// sources.
+type githubAPI struct {
+	Token string `yaml:"token"`
+	// BaseURL and UploadURL are the API URLs of a GitHub Enterprise install,
+	// ie. https://github.example.com/api/v3/. The upload URL defaults to the
+	// base URL.
+	BaseURL   string `yaml:"base_url"`
+	UploadURL string `yaml:"upload_url"`
+	// Private includes the private repositories of the owners the token can
+	// see.
+	Private bool `yaml:"private"`
+}
+
+// githubAPIFromFlags returns the GitHub API settings passed on the command
+// line.
+func githubAPIFromFlags() githubAPI {
+	return githubAPI{
+		Token:     githubToken,
+		BaseURL:   githubBaseURL,
+		UploadURL: githubUploadURL,
+	}
+}
+
+// overrideFlags sets the GitHub API settings that were passed on the command
+// line, since they win over the config file.
+func (a *githubAPI) overrideFlags() {
+	if flagPassed("github-token") {
+		a.Token = githubToken
+	}
+	if flagPassed("github-url") {
+		a.BaseURL = githubBaseURL
+	}
+	if flagPassed("github-upload-url") {
+		a.UploadURL = githubUploadURL
+	}
+}
+
+// newClient returns a GitHub API client using the shared HTTP client, so the
+// requests are retried and wait out the rate limits. Without a token, the
+// requests are anonymous.
+func (a githubAPI) newClient() (*github.Client, error) {
+	if a.Private && len(a.Token) <= 0 {
+		return nil, errors.New("a github token is needed to include private repos")
+	}
+
+	client := httpClient
+	if len(a.Token) > 0 {
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
+		client = oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: a.Token}))
+		client.Timeout = httpClient.Timeout
+	}
+
+	if len(a.BaseURL) <= 0 {
+		return github.NewClient(client), nil
+	}
+
+	uploadURL := a.UploadURL
+	if len(uploadURL) <= 0 {
+		uploadURL = a.BaseURL
+	}
+	c, err := github.NewEnterpriseClient(a.BaseURL, uploadURL, client)
+	if err != nil {
+		return nil, fmt.Errorf("creating github client for %q failed: %v", a.BaseURL, err)
+	}
+	return c, nil
+}
+
+// isGitHubNotFound returns true if the error, or the error it wraps, is a
+// 404 from the GitHub API.
+func isGitHubNotFound(err error) bool {
+	var e *github.ErrorResponse
+	return errors.As(err, &e) && e.Response != nil && e.Response.StatusCode == http.StatusNotFound
+}
+
+// githubRepoListTTL is how long the repositories listed for an owner are
+// reused, so the GitHub data sources refreshing at the same time list them
+// once between them.
+const githubRepoListTTL = time.Minute
+
+// githubRepoListKey identifies the repositories listed for an owner. They
+// differ per API, token and whether private repositories are included.
+type githubRepoListKey struct {
+	api   githubAPI
+	owner string
+}
+
+// githubRepoList holds the repositories listed for an owner. While they are
+// being listed, call is set so the other data sources wait for them instead
+// of listing them again.
+type githubRepoList struct {
+	repos    []*github.Repository
+	listedAt time.Time
+	call     *githubRepoListCall
+}
+
+// githubRepoListCall is a listing of the repositories of an owner. done is
+// closed once repos and err are set.
+type githubRepoListCall struct {
+	done  chan struct{}
+	repos []*github.Repository
+	err   error
+}
+
+// githubRepoLists holds the repositories listed for each owner. Its mutex
+// guards the lists but is not held while they are listed.
+var githubRepoLists = struct {
+	sync.Mutex
+	lists map[githubRepoListKey]*githubRepoList
+}{lists: map[githubRepoListKey]*githubRepoList{}}
+
+// listGitHubRepos returns the repositories of the owner that should be
+// shown. Forks and archived repositories are skipped since we don't care
+// about them. Private repositories are only listed if the API settings
+// include them. The repositories are shared with the other data sources so
+// they must not be modified.
+func listGitHubRepos(ctx context.Context, client *github.Client, api githubAPI, owner githubOwner) ([]*github.Repository, error) {
+	key := githubRepoListKey{api: api, owner: strings.ToLower(owner.Name)}
+
+	githubRepoLists.Lock()
+	l, ok := githubRepoLists.lists[key]
+	if !ok {
+		l = &githubRepoList{}
+		githubRepoLists.lists[key] = l
+	}
+	call := l.call
+	if call == nil && time.Since(l.listedAt) >= githubRepoListTTL {
+		// List the repositories for everyone asking for them meanwhile.
+		call = &githubRepoListCall{done: make(chan struct{})}
+		l.call = call
+		githubRepoLists.Unlock()
+
+		call.repos, call.err = fetchGitHubRepos(ctx, client, owner.Name, api.Private)
+
+		githubRepoLists.Lock()
+		if call.err == nil {
+			l.repos = call.repos
+			l.listedAt = time.Now()
+		}
+		l.call = nil
+		close(call.done)
+	}
+	all := l.repos
+	githubRepoLists.Unlock()
+
+	if call != nil {
+		// Wait for the listing, unless we are done first.
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if call.err != nil {
+			return nil, call.err
+		}
+		all = call.repos
+	}
+
+	repos := []*github.Repository{}
+	for _, repo := range all {
+		if matchPatterns(owner.Include, owner.Exclude, repo.GetName()) {
+			repos = append(repos, repo)
+		}
+	}
+	return repos, nil
+}
+
+// fetchGitHubRepos lists the repositories of the owner, skipping forks,
+// archived repositories and, unless private is true, private repositories.
+func fetchGitHubRepos(ctx context.Context, client *github.Client, owner string, private bool) ([]*github.Repository, error) {
+	list, err := githubRepoLister(ctx, client, owner, private)
+	if err != nil {
+		return nil, err
+	}
+
+	var repos []*github.Repository
+	opt := github.ListOptions{PerPage: 100}
+	for {
+		reposResp, resp, err := list(opt)
+		if err != nil {
+			return nil, fmt.Errorf("listing repos for %q failed: %v", owner, err)
+		}
+
+		for _, repo := range reposResp {
+			if repo.GetFork() || repo.GetArchived() {
+				// Continue early if its a fork or archived because we don't care.
+				continue
+			}
+			if repo.GetPrivate() && !private {
+				continue
+			}
+			repos = append(repos, repo)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	return repos, nil
+}
+
+// githubRepoLister returns a function listing a page of the repositories of
+// the owner. Only the public repositories of other users can be listed, but
+// the private repositories of organizations and the authenticated user are
+// listed too if private is true.
+func githubRepoLister(ctx context.Context, client *github.Client, owner string, private bool) (func(opt github.ListOptions) ([]*github.Repository, *github.Response, error), error) {
+	if !private {
+		return func(opt github.ListOptions) ([]*github.Repository, *github.Response, error) {
+			return client.Repositories.List(ctx, owner, &github.RepositoryListOptions{ListOptions: opt, Type: "sources"})
+		}, nil
+	}
+
+	user, _, err := client.Users.Get(ctx, owner)
+	if err != nil {
+		return nil, fmt.Errorf("getting github user %q failed: %v", owner, err)
+	}
+	if user.GetType() == "Organization" {
+		return func(opt github.ListOptions) ([]*github.Repository, *github.Response, error) {
+			return client.Repositories.ListByOrg(ctx, owner, &github.RepositoryListByOrgOptions{ListOptions: opt, Type: "sources"})
+		}, nil
+	}
+
+	me, err := githubLogin(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(me, owner) {
+		return func(opt github.ListOptions) ([]*github.Repository, *github.Response, error) {
+			return client.Repositories.List(ctx, owner, &github.RepositoryListOptions{ListOptions: opt, Type: "sources"})
+		}, nil
+	}
+	return func(opt github.ListOptions) ([]*github.Repository, *github.Response, error) {
+		// Listing the repositories of the authenticated user is the only
+		// way to get their private ones.
+		return client.Repositories.List(ctx, "", &github.RepositoryListOptions{ListOptions: opt, Affiliation: "owner"})
+	}, nil
+}
+
+// githubLogin returns the login of the user the client is authenticated as.
+func githubLogin(ctx context.Context, client *github.Client) (string, error) {
+	me, _, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return "", fmt.Errorf("getting the authenticated github user failed: %v", err)
+	}
+	return me.GetLogin(), nil
+}
+
+// forEachGitHubRepo calls fn for each of the repositories, at most
+// concurrency at a time. It returns the last error returned by fn, if any.
+func forEachGitHubRepo(ctx context.Context, repos []*github.Repository, concurrency int, fn func(i int, repo *github.Repository) error) error {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		lastErr error
+	)
+	sem := make(chan struct{}, concurrency)
+	for i, repo := range repos {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+
+		wg.Add(1)
+		go func(i int, repo *github.Repository) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := fn(i, repo); err != nil {
+				mu.Lock()
+				lastErr = err
+				mu.Unlock()
+			}
+		}(i, repo)
+	}
+	wg.Wait()
+
+	return lastErr
+}
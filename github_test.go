@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+)
+
+// newGitHubServer returns a client for a test GitHub API listing a single
+// repository for every owner once release is closed, and a counter of the
+// listings. If fail is true, the listings fail instead.
+func newGitHubServer(t *testing.T, release chan struct{}, fail bool) (*github.Client, *int32) {
+	var listed int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&listed, 1)
+		<-release
+
+		if fail {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`[{"name":"tdash"}]`))
+	}))
+	t.Cleanup(srv.Close)
+
+	// Forget the repositories listed by the other tests.
+	githubRepoLists.Lock()
+	githubRepoLists.lists = map[githubRepoListKey]*githubRepoList{}
+	githubRepoLists.Unlock()
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+	return client, &listed
+}
+
+// waitForListing waits until the test GitHub API got a listing.
+func waitForListing(t *testing.T, listed *int32) {
+	for i := 0; atomic.LoadInt32(listed) <= 0; i++ {
+		if i >= 500 {
+			t.Fatal("the repositories were never listed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestListGitHubReposShared(t *testing.T) {
+	release := make(chan struct{})
+	client, listed := newGitHubServer(t, release, false)
+	owner := githubOwner{Name: "shared"}
+
+	// Start listing and wait for it to reach the server.
+	type result struct {
+		repos []*github.Repository
+		err   error
+	}
+	results := make(chan result, 2)
+	list := func() {
+		repos, err := listGitHubRepos(context.Background(), client, githubAPI{}, owner)
+		results <- result{repos: repos, err: err}
+	}
+	go list()
+	waitForListing(t, listed)
+	go list()
+
+	// A data source that is done does not wait for the listing.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := listGitHubRepos(ctx, client, githubAPI{}, owner); err != context.Canceled {
+		t.Fatalf("got error %v while waiting for the listing, want %v", err, context.Canceled)
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		r := <-results
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		if len(r.repos) != 1 || r.repos[0].GetName() != "tdash" {
+			t.Errorf("got repos %v, want tdash", r.repos)
+		}
+	}
+	if n := atomic.LoadInt32(listed); n != 1 {
+		t.Errorf("the repositories were listed %d times, want once", n)
+	}
+
+	// The repositories are reused until they are too old.
+	if _, err := listGitHubRepos(context.Background(), client, githubAPI{}, owner); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(listed); n != 1 {
+		t.Errorf("the repositories were listed %d times, want once", n)
+	}
+}
+
+func TestListGitHubReposError(t *testing.T) {
+	release := make(chan struct{})
+	client, listed := newGitHubServer(t, release, true)
+	owner := githubOwner{Name: "failing"}
+
+	errs := make(chan error, 2)
+	list := func() {
+		_, err := listGitHubRepos(context.Background(), client, githubAPI{}, owner)
+		errs <- err
+	}
+	go list()
+	waitForListing(t, listed)
+	go list()
+
+	// Give the second data source time to wait for the listing.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err == nil {
+			t.Fatal("a failed listing did not return an error")
+		}
+	}
+	if n := atomic.LoadInt32(listed); n != 1 {
+		t.Errorf("the repositories were listed %d times, want once", n)
+	}
+
+	// A failed listing is not reused.
+	if _, err := listGitHubRepos(context.Background(), client, githubAPI{}, owner); err == nil {
+		t.Fatal("a failed listing did not return an error")
+	}
+	if n := atomic.LoadInt32(listed); n != 2 {
+		t.Errorf("the repositories were listed %d times, want twice", n)
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gizak/termui"
+	"github.com/google/go-github/v32/github"
+)
+
+func init() {
+	registerSource("githubactions", func() Source { return &githubActionsSource{} })
+}
+
+// githubRun holds the latest run of a GitHub Actions workflow on a branch.
+type githubRun struct {
+	repo       string
+	branch     string
+	workflow   string
+	number     int
+	status     string
+	conclusion string
+	event      string
+	duration   time.Duration
+	updatedAt  time.Time
+}
+
+// passed returns true if the run finished without failing.
+func (r githubRun) passed() bool {
+	if r.status != "completed" {
+		return false
+	}
+	switch r.conclusion {
+	case "success", "neutral", "skipped":
+		return true
+	}
+	return false
+}
+
+// failed returns true if the run finished and failed.
+func (r githubRun) failed() bool {
+	if r.status != "completed" {
+		return false
+	}
+	switch r.conclusion {
+	case "failure", "timed_out", "startup_failure", "action_required":
+		return true
+	}
+	return false
+}
+
+// githubActionsData holds the GitHub Actions workflow runs for an owner.
+type githubActionsData struct {
+	owner string
+	runs  []githubRun
+}
+
+// githubActionsSettings holds the settings for a GitHub Actions data source.
+type githubActionsSettings struct {
+	githubRepoSettings `yaml:",inline"`
+
+	// Branches are the branches to show the workflow runs for. If none are
+	// set, the default branch of each repository is shown.
+	Branches []string `yaml:"branches"`
+}
+
+// githubActionsSource is a Source for GitHub Actions workflow runs.
+type githubActionsSource struct {
+	settings githubActionsSettings
+	client   *github.Client
+}
+
+// Name implements Source.
+func (s *githubActionsSource) Name() string {
+	return "GitHub Actions"
+}
+
+// Configure implements Source.
+func (s *githubActionsSource) Configure(cfg *sourceConfig) (bool, error) {
+	// Get the settings from the flags and config file.
+	s.settings = githubActionsSettings{
+		githubRepoSettings: githubRepoSettingsFromFlags(),
+	}
+	if err := cfg.decode(&s.settings); err != nil {
+		return false, err
+	}
+
+	var (
+		ok  bool
+		err error
+	)
+	s.client, ok, err = s.settings.configure()
+	if !ok || err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// DefaultInterval implements Intervaler. Getting the latest runs takes a
+// request for each workflow on each branch of every repository, which would
+// use up the rate limit if they were fetched every few minutes.
+func (s *githubActionsSource) DefaultInterval() time.Duration {
+	return 10 * time.Minute
+}
+
+// Fetch implements Source.
+func (s *githubActionsSource) Fetch(ctx context.Context) (interface{}, error) {
+	data := []githubActionsData{}
+
+	for _, owner := range s.settings.Owners {
+		d := githubActionsData{owner: owner.Name}
+
+		repos, err := listGitHubRepos(ctx, s.client, s.settings.githubAPI, owner)
+		if err != nil {
+			return nil, err
+		}
+
+		runs := make([][]githubRun, len(repos))
+		err = forEachGitHubRepo(ctx, repos, s.settings.Concurrency, func(i int, repo *github.Repository) error {
+			r, err := s.getRuns(ctx, owner.Name, repo)
+			runs[i] = r
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// Add the runs in the same order as the repositories.
+		for _, r := range runs {
+			d.runs = append(d.runs, r...)
+		}
+
+		data = append(data, d)
+	}
+
+	return data, nil
+}
+
+// getRuns returns the latest run of each workflow of the repository on each
+// of the branches to show.
+func (s *githubActionsSource) getRuns(ctx context.Context, owner string, repo *github.Repository) ([]githubRun, error) {
+	branches := s.settings.Branches
+	if len(branches) <= 0 {
+		// Empty repositories have no default branch, so there is nothing
+		// to show for them.
+		if len(repo.GetDefaultBranch()) <= 0 {
+			return nil, nil
+		}
+		branches = []string{repo.GetDefaultBranch()}
+	}
+
+	// Get the workflows.
+	var workflows []*github.Workflow
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		w, resp, err := s.client.Actions.ListWorkflows(ctx, owner, repo.GetName(), opt)
+		if err != nil {
+			// Repos with Actions turned off have no workflows, so we might
+			// as well error silently if we get a 404.
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("listing workflows for %q failed: %v", repo.GetFullName(), err)
+		}
+		workflows = append(workflows, w.Workflows...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	runs := []githubRun{}
+	for _, branch := range branches {
+		// Get the latest run of each workflow on its own, since a workflow
+		// that has not run for a while can be far down the runs of the
+		// repository.
+		for _, workflow := range workflows {
+			wr, _, err := s.client.Actions.ListWorkflowRunsByID(ctx, owner, repo.GetName(), workflow.GetID(), &github.ListWorkflowRunsOptions{
+				Branch:      branch,
+				ListOptions: github.ListOptions{PerPage: 1},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("listing runs of workflow %q on %s for %q failed: %v", workflow.GetName(), branch, repo.GetFullName(), err)
+			}
+			if len(wr.WorkflowRuns) <= 0 {
+				continue
+			}
+			run := wr.WorkflowRuns[0]
+
+			r := githubRun{
+				repo:       repo.GetName(),
+				branch:     branch,
+				workflow:   workflow.GetName(),
+				number:     run.GetRunNumber(),
+				status:     run.GetStatus(),
+				conclusion: run.GetConclusion(),
+				event:      run.GetEvent(),
+				updatedAt:  run.GetUpdatedAt().Time,
+			}
+			if r.status == "completed" {
+				r.duration = run.GetUpdatedAt().Sub(run.GetCreatedAt().Time)
+			} else {
+				r.duration = time.Since(run.GetCreatedAt().Time)
+			}
+			runs = append(runs, r)
+		}
+	}
+
+	return runs, nil
+}
+
+// Render implements Source.
+func (s *githubActionsSource) Render(v interface{}) []*termui.Row {
+	tables := []termui.GridBufferer{}
+
+	for _, d := range v.([]githubActionsData) {
+		// Initialize the table.
+		table := termui.NewTable()
+		rows := [][]string{
+			{"repo", "branch", "workflow", "run", "status", "conclusion", "duration", "event", "updated at"},
+		}
+		redrows := []int{}
+		otherrows := []int{}
+
+		for _, run := range d.runs {
+			if showAllBuilds || !run.passed() {
+				rows = append(rows, []string{
+					run.repo,
+					run.branch,
+					run.workflow,
+					fmt.Sprintf("#%d", run.number),
+					run.status,
+					run.conclusion,
+					printDuration(run.duration),
+					run.event,
+					run.updatedAt.Local().Format("Mon, Jan 02 15:04 MST"),
+				})
+
+				if run.failed() {
+					redrows = append(redrows, len(rows)-1)
+				} else if !run.passed() {
+					otherrows = append(otherrows, len(rows)-1)
+				}
+			}
+		}
+
+		if len(rows) <= 1 {
+			// continue early if we have no data
+			continue
+		}
+
+		// Set the rows.
+		table.Rows = rows
+
+		// Set the default colors and settings.
+		table.FgColor = termui.ColorWhite
+		table.BgColor = termui.ColorDefault
+		table.TextAlign = termui.AlignLeft
+		table.Border = true
+		table.Separator = true
+		table.Block.BorderLabel = "GitHub Actions workflow runs for " + d.owner
+		table.Analysis()
+		table.SetSize()
+		// Set the color to red for the red rows
+		for _, br := range redrows {
+			table.FgColors[br] = termui.ColorRed
+		}
+		// Set the color to yellow for the other rows
+		for _, br := range otherrows {
+			table.FgColors[br] = termui.ColorYellow
+		}
+
+		tables = append(tables, table)
+	}
+
+	return columnRows(tables)
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gizak/termui"
+	"github.com/google/go-github/v32/github"
+	"github.com/sirupsen/logrus"
+)
+
+func init() {
+	registerSource("githubissues", func() Source { return &githubIssuesSource{} })
+}
+
+// githubIssue holds an issue matching a query.
+type githubIssue struct {
+	repo      string
+	number    int
+	title     string
+	createdAt time.Time
+	// isNew is true if the issue was created since the last fetch.
+	isNew bool
+}
+
+// githubIssuesData holds the issues matching a query.
+type githubIssuesData struct {
+	query string
+	total int
+	// newCount is the number of the top issues that are new.
+	newCount int
+	issues   []githubIssue
+}
+
+// githubIssuesSettings holds the settings for a GitHub issues data source.
+type githubIssuesSettings struct {
+	githubSettings `yaml:",inline"`
+
+	// Queries are GitHub issue search queries, ie. "label:bug no:assignee".
+	// They only match the open issues of the owners.
+	Queries []string `yaml:"queries"`
+	// Top is the number of the newest issues to show for each query.
+	Top int `yaml:"top"`
+}
+
+// githubIssuesSource is a Source for the counts and newest GitHub issues
+// matching search queries.
+type githubIssuesSource struct {
+	settings githubIssuesSettings
+	client   *github.Client
+
+	// lastFetch is when the last fetch started, so the issues created since
+	// can be highlighted. It is zero before the first fetch.
+	lastFetch time.Time
+}
+
+// Name implements Source.
+func (s *githubIssuesSource) Name() string {
+	return "GitHub issues"
+}
+
+// Configure implements Source.
+func (s *githubIssuesSource) Configure(cfg *sourceConfig) (bool, error) {
+	// Get the settings from the flags and config file.
+	s.settings = githubIssuesSettings{
+		githubSettings: githubSettingsFromFlags(),
+		Top:            5,
+	}
+	if err := cfg.decode(&s.settings); err != nil {
+		return false, err
+	}
+
+	var (
+		ok  bool
+		err error
+	)
+	s.client, ok, err = s.settings.configure()
+	if !ok || err != nil {
+		return false, err
+	}
+
+	// The search API cannot match the patterns for the repositories, so
+	// don't let them be silently ignored.
+	for _, owner := range s.settings.Owners {
+		if len(owner.Include) > 0 || len(owner.Exclude) > 0 {
+			return false, fmt.Errorf("github owner %q cannot have include or exclude patterns for issues, use repo: or -repo: in the queries instead", owner.Name)
+		}
+	}
+
+	// Check that the queries is not empty.
+	if len(s.settings.Queries) <= 0 {
+		logrus.Warn("GitHub issue queries cannot be empty")
+		return false, nil
+	}
+
+	if s.settings.Top <= 0 {
+		s.settings.Top = 1
+	}
+
+	return true, nil
+}
+
+// Fetch implements Source.
+func (s *githubIssuesSource) Fetch(ctx context.Context) (interface{}, error) {
+	// Only match the open issues of the owners.
+	qualifiers := []string{"is:issue", "is:open"}
+	for _, owner := range s.settings.Owners {
+		qualifiers = append(qualifiers, "user:"+owner.Name)
+	}
+	if !s.settings.Private {
+		qualifiers = append(qualifiers, "is:public")
+	}
+
+	// Run the queries one at a time, since the search API has a much lower
+	// rate limit.
+	data := []githubIssuesData{}
+	fetchedAt := time.Now()
+	for _, query := range s.settings.Queries {
+		result, _, err := s.client.Search.Issues(ctx, query+" "+strings.Join(qualifiers, " "), &github.SearchOptions{
+			Sort:        "created",
+			Order:       "desc",
+			ListOptions: github.ListOptions{PerPage: s.settings.Top},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("searching for issues matching %q failed: %v", query, err)
+		}
+
+		d := githubIssuesData{
+			query: query,
+			total: result.GetTotal(),
+		}
+		for _, issue := range result.Issues {
+			i := githubIssue{
+				repo:      repoFromURL(issue.GetRepositoryURL()),
+				number:    issue.GetNumber(),
+				title:     issue.GetTitle(),
+				createdAt: issue.GetCreatedAt(),
+			}
+			// Only issues created since the last fetch are new, not the older
+			// ones that moved into the top issues as others were closed.
+			if !s.lastFetch.IsZero() && i.createdAt.After(s.lastFetch) {
+				i.isNew = true
+				d.newCount++
+			}
+
+			d.issues = append(d.issues, i)
+		}
+
+		data = append(data, d)
+	}
+	s.lastFetch = fetchedAt
+
+	return data, nil
+}
+
+// repoFromURL returns the owner and name of a repository from its API URL,
+// ie. jessfraz/tdash for https://api.github.com/repos/jessfraz/tdash.
+func repoFromURL(u string) string {
+	parts := strings.Split(strings.TrimSuffix(u, "/"), "/")
+	if len(parts) < 2 {
+		return u
+	}
+	return strings.Join(parts[len(parts)-2:], "/")
+}
+
+// Render implements Source.
+func (s *githubIssuesSource) Render(v interface{}) []*termui.Row {
+	lists := []termui.GridBufferer{}
+
+	for _, d := range v.([]githubIssuesData) {
+		items := []string{}
+		for _, issue := range d.issues {
+			// Make sure the title is not taken as termui markup.
+			title := strings.Replace(issue.title, "](", "] (", -1)
+			line := fmt.Sprintf("%s#%d %s (%s)", issue.repo, issue.number, title, printDuration(time.Since(issue.createdAt)))
+			if issue.isNew {
+				items = append(items, highlightLine(line, "fg-green,fg-bold"))
+			} else {
+				items = append(items, "  "+line)
+			}
+		}
+
+		label := fmt.Sprintf("%s (%d)", d.query, d.total)
+		if d.newCount > 0 {
+			label = fmt.Sprintf("%s (%d, %d new)", d.query, d.total, d.newCount)
+		}
+
+		list := termui.NewList()
+		list.Items = items
+		list.ItemFgColor = termui.ColorWhite
+		list.BorderLabel = label
+		list.Height = s.settings.Top + 2
+		if d.newCount > 0 {
+			list.BorderFg = termui.ColorGreen
+		}
+
+		lists = append(lists, list)
+	}
+
+	return columnRows(lists)
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gizak/termui"
+	"github.com/google/go-github/v32/github"
+)
+
+func init() {
+	registerSource("githubpulls", func() Source { return &githubPullsSource{} })
+}
+
+// githubPull holds an open pull request that needs attention and why.
+type githubPull struct {
+	repo      string
+	number    int
+	title     string
+	author    string
+	createdAt time.Time
+	// reasons holds why the pull request needs attention, ie. "review
+	// requested" or "checks failing".
+	reasons []string
+	// broken is true if the checks of the pull request are failing or it
+	// has merge conflicts.
+	broken bool
+}
+
+// pullChecksGrace is how long after a pull request was updated its checks
+// are looked for again if it had none, since they can take a while to start.
+const pullChecksGrace = time.Hour
+
+// pullState holds the state of the checks and mergeability of an open pull
+// request, along with the commits they were for, so they are only fetched
+// again once they could have changed.
+type pullState struct {
+	head      string
+	base      string
+	checks    string
+	mergeable string
+}
+
+// githubPullsSettings holds the settings for a GitHub pull requests data
+// source.
+type githubPullsSettings struct {
+	githubRepoSettings `yaml:",inline"`
+
+	// User is the login of the user whose review requests are shown. It
+	// defaults to the user the token belongs to.
+	User string `yaml:"user"`
+	// Teams are the slugs of the teams whose review requests are shown.
+	Teams []string `yaml:"teams"`
+	// Stale is the number of days after which an open pull request is
+	// stale, 0 turns it off.
+	Stale int `yaml:"stale"`
+}
+
+// githubPullsSource is a Source for the open pull requests on GitHub that
+// need a review, are failing their checks, are stale or have merge
+// conflicts.
+type githubPullsSource struct {
+	settings githubPullsSettings
+	client   *github.Client
+
+	// user is the login of the user whose review requests are shown.
+	user string
+
+	mu sync.Mutex
+	// states holds the state of each open pull request from the last
+	// fetch by its repository and number.
+	states map[string]pullState
+}
+
+// Name implements Source.
+func (s *githubPullsSource) Name() string {
+	return "GitHub pull requests"
+}
+
+// Configure implements Source.
+func (s *githubPullsSource) Configure(cfg *sourceConfig) (bool, error) {
+	// Get the settings from the flags and config file.
+	s.settings = githubPullsSettings{
+		githubRepoSettings: githubRepoSettingsFromFlags(),
+		Stale:              14,
+	}
+	if err := cfg.decode(&s.settings); err != nil {
+		return false, err
+	}
+
+	var (
+		ok  bool
+		err error
+	)
+	s.client, ok, err = s.settings.configure()
+	if !ok || err != nil {
+		return false, err
+	}
+	s.user = s.settings.User
+
+	return true, nil
+}
+
+// Fetch implements Source.
+func (s *githubPullsSource) Fetch(ctx context.Context) (interface{}, error) {
+	data := []githubPull{}
+
+	// Get the user the token belongs to, once, if the user was not set.
+	if len(s.user) <= 0 && len(s.settings.Token) > 0 {
+		login, err := githubLogin(ctx, s.client)
+		if err != nil {
+			return nil, err
+		}
+		s.user = login
+	}
+
+	// Only keep the states of the pull requests that are still open.
+	states := map[string]pullState{}
+
+	for _, owner := range s.settings.Owners {
+		repos, err := listGitHubRepos(ctx, s.client, s.settings.githubAPI, owner)
+		if err != nil {
+			return nil, err
+		}
+
+		pulls := make([][]githubPull, len(repos))
+		err = forEachGitHubRepo(ctx, repos, s.settings.Concurrency, func(i int, repo *github.Repository) error {
+			p, err := s.getPulls(ctx, owner.Name, repo, states)
+			pulls[i] = p
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range pulls {
+			data = append(data, p...)
+		}
+	}
+
+	s.mu.Lock()
+	s.states = states
+	s.mu.Unlock()
+
+	// Show the oldest pull requests first.
+	sort.SliceStable(data, func(i, j int) bool {
+		return data[i].createdAt.Before(data[j].createdAt)
+	})
+
+	return data, nil
+}
+
+// getPulls returns the open pull requests of the repository that need
+// attention, adding their states to states.
+func (s *githubPullsSource) getPulls(ctx context.Context, owner string, repo *github.Repository, states map[string]pullState) ([]githubPull, error) {
+	opt := &github.PullRequestListOptions{
+		State:       "open",
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	var prs []*github.PullRequest
+	for {
+		prsResp, resp, err := s.client.PullRequests.List(ctx, owner, repo.GetName(), opt)
+		if err != nil {
+			return nil, fmt.Errorf("listing pull requests for %q failed: %v", repo.GetFullName(), err)
+		}
+		prs = append(prs, prsResp...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	pulls := []githubPull{}
+	for _, pr := range prs {
+		pull := githubPull{
+			repo:      repo.GetFullName(),
+			number:    pr.GetNumber(),
+			title:     pr.GetTitle(),
+			author:    pr.GetUser().GetLogin(),
+			createdAt: pr.GetCreatedAt(),
+		}
+
+		if s.reviewRequested(pr) {
+			pull.reasons = append(pull.reasons, "review requested")
+		}
+
+		state, err := s.getPullState(ctx, owner, repo, pr)
+		if err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		states[fmt.Sprintf("%s#%d", pull.repo, pull.number)] = state
+		s.mu.Unlock()
+
+		if state.checks == "failure" {
+			pull.reasons = append(pull.reasons, "checks failing")
+			pull.broken = true
+		}
+
+		if s.settings.Stale > 0 && time.Since(pull.createdAt) > time.Duration(s.settings.Stale)*24*time.Hour {
+			pull.reasons = append(pull.reasons, "stale")
+		}
+
+		if state.mergeable == "dirty" {
+			pull.reasons = append(pull.reasons, "merge conflicts")
+			pull.broken = true
+		}
+
+		if len(pull.reasons) > 0 {
+			pulls = append(pulls, pull)
+		}
+	}
+
+	return pulls, nil
+}
+
+// getPullState returns the state of the checks and mergeability of the pull
+// request. They take a request or two each, so they are reused from the last
+// fetch unless the head or base commit moved or they had not settled yet.
+func (s *githubPullsSource) getPullState(ctx context.Context, owner string, repo *github.Repository, pr *github.PullRequest) (pullState, error) {
+	s.mu.Lock()
+	prev, ok := s.states[fmt.Sprintf("%s#%d", repo.GetFullName(), pr.GetNumber())]
+	s.mu.Unlock()
+
+	state := pullState{
+		head: pr.GetHead().GetSHA(),
+		base: pr.GetBase().GetSHA(),
+	}
+	if ok && prev.head == state.head {
+		state.checks = prev.checks
+		if prev.base == state.base {
+			state.mergeable = prev.mergeable
+		}
+	}
+
+	// Get the checks for the head commit, unless they had all finished. If
+	// there were none, they might not have started yet, so check again for
+	// a while after the pull request was updated.
+	recent := time.Since(pr.GetUpdatedAt()) < pullChecksGrace
+	if state.checks == "" || state.checks == "pending" || (state.checks == "none" && recent) {
+		_, contexts, err := getCommitContexts(ctx, s.client, owner, repo, state.head)
+		if err != nil {
+			return pullState{}, err
+		}
+		state.checks = combinedState(contexts)
+		if len(contexts) <= 0 {
+			state.checks = "none"
+		}
+	}
+
+	// The list of pull requests does not say if they can be merged, so get
+	// the pull request itself, unless we know already. GitHub works it out
+	// in the background, so it is unknown for a while after a push.
+	if state.mergeable == "" || state.mergeable == "unknown" {
+		full, _, err := s.client.PullRequests.Get(ctx, owner, repo.GetName(), pr.GetNumber())
+		if err != nil {
+			return pullState{}, fmt.Errorf("getting pull request %d for %q failed: %v", pr.GetNumber(), repo.GetFullName(), err)
+		}
+		state.mergeable = full.GetMergeableState()
+		if state.mergeable == "" {
+			state.mergeable = "unknown"
+		}
+	}
+
+	return state, nil
+}
+
+// reviewRequested returns true if a review of the pull request was requested
+// from the user or one of the teams.
+func (s *githubPullsSource) reviewRequested(pr *github.PullRequest) bool {
+	for _, u := range pr.RequestedReviewers {
+		if len(s.user) > 0 && strings.EqualFold(u.GetLogin(), s.user) {
+			return true
+		}
+	}
+	for _, t := range pr.RequestedTeams {
+		for _, slug := range s.settings.Teams {
+			if strings.EqualFold(t.GetSlug(), slug) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Render implements Source.
+func (s *githubPullsSource) Render(v interface{}) []*termui.Row {
+	// Initialize the table.
+	table := termui.NewTable()
+	rows := [][]string{
+		{"repo", "pull request", "title", "author", "age", "needs attention for"},
+	}
+	redrows := []int{}
+	otherrows := []int{}
+
+	for _, pull := range v.([]githubPull) {
+		// Make sure the title is not taken as termui markup.
+		title := strings.Replace(pull.title, "](", "] (", -1)
+		if r := []rune(title); len(r) > 40 {
+			title = string(r[:39]) + "…"
+		}
+
+		rows = append(rows, []string{
+			pull.repo,
+			fmt.Sprintf("#%d", pull.number),
+			title,
+			pull.author,
+			printDuration(time.Since(pull.createdAt)),
+			strings.Join(pull.reasons, ", "),
+		})
+
+		if pull.broken {
+			redrows = append(redrows, len(rows)-1)
+		} else {
+			otherrows = append(otherrows, len(rows)-1)
+		}
+	}
+
+	if len(rows) <= 1 {
+		// return early if we have no data
+		return nil
+	}
+
+	// Set the rows.
+	table.Rows = rows
+
+	// Set the default colors and settings.
+	table.FgColor = termui.ColorWhite
+	table.BgColor = termui.ColorDefault
+	table.TextAlign = termui.AlignLeft
+	table.Border = true
+	table.Block.BorderLabel = "GitHub pull requests that need attention"
+	table.Analysis()
+	table.SetSize()
+	// Set the color to red for the red rows
+	for _, br := range redrows {
+		table.FgColors[br] = termui.ColorRed
+	}
+	// Set the color to yellow for the other rows
+	for _, br := range otherrows {
+		table.FgColors[br] = termui.ColorYellow
+	}
+
+	return []*termui.Row{termui.NewRow(termui.NewCol(12, 0, table))}
+}
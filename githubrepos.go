@@ -0,0 +1,472 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/gizak/termui"
+	"github.com/google/go-github/v32/github"
+	"github.com/sirupsen/logrus"
+)
+
+func init() {
+	registerSource("githubrepos", func() Source { return &githubReposSource{} })
+}
+
+const (
+	// trafficDays is the number of days of traffic GitHub keeps.
+	trafficDays = 14
+	// keepSnapshots is the number of days of snapshots of the counts of each
+	// repository kept in the stats file.
+	keepSnapshots = 7
+)
+
+// githubRepoStats holds the counts and traffic of a repository.
+type githubRepoStats struct {
+	repo   string
+	counts repoSnapshot
+	// deltas holds how much the counts changed since the last snapshot
+	// before today, if there is one.
+	deltas *repoSnapshot
+	// traffic is true if we admin the repository, so we can get its
+	// traffic.
+	traffic   bool
+	views     []int
+	clones    []int
+	viewsSum  int
+	clonesSum int
+	referrers []*github.TrafficReferrer
+}
+
+// githubReposData holds the stats of the repositories of an owner.
+type githubReposData struct {
+	owner string
+	repos []githubRepoStats
+}
+
+// repoSnapshot holds the counts of a repository on a day. The snapshots
+// are kept in the stats file so the counts can be compared to yesterday's.
+type repoSnapshot struct {
+	Date       string `json:"date"`
+	Stars      int    `json:"stars"`
+	Forks      int    `json:"forks"`
+	OpenIssues int    `json:"open_issues"`
+}
+
+// githubReposSettings holds the settings for a GitHub repos data source.
+type githubReposSettings struct {
+	githubRepoSettings `yaml:",inline"`
+
+	// Traffic shows the views, clones and top referrers over the last 14
+	// days of the repositories we admin. It is on by default.
+	Traffic bool `yaml:"traffic"`
+	// TrafficTop is the number of the most viewed repositories to show the
+	// traffic of, so the panel does not take over the screen.
+	TrafficTop int `yaml:"traffic_top"`
+}
+
+// githubReposSource is a Source for the health of GitHub repositories: their
+// stars, forks, open issues and traffic.
+type githubReposSource struct {
+	settings githubReposSettings
+	client   *github.Client
+
+	// statsFile is where snapshots is kept between runs. It holds the
+	// snapshots of the counts of each repository by its full name.
+	statsFile string
+	snapshots map[string][]repoSnapshot
+}
+
+// Name implements Source.
+func (s *githubReposSource) Name() string {
+	return "GitHub repos"
+}
+
+// Configure implements Source.
+func (s *githubReposSource) Configure(cfg *sourceConfig) (bool, error) {
+	// Get the settings from the flags and config file.
+	s.settings = githubReposSettings{
+		githubRepoSettings: githubRepoSettingsFromFlags(),
+		Traffic:            true,
+		TrafficTop:         5,
+	}
+	if err := cfg.decode(&s.settings); err != nil {
+		return false, err
+	}
+
+	var (
+		ok  bool
+		err error
+	)
+	s.client, ok, err = s.settings.configure()
+	if !ok || err != nil {
+		return false, err
+	}
+
+	if s.settings.TrafficTop <= 0 {
+		s.settings.TrafficTop = 1
+	}
+
+	// Read the snapshots from the last time we ran.
+	s.statsFile = filepath.Join(dashDir, "stats", cfg.Name+".json")
+	s.snapshots, err = readSnapshots(s.statsFile)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// DefaultInterval implements Intervaler. The counts change slowly and the
+// traffic takes three requests for each repository, so there is no point
+// getting them every few minutes.
+func (s *githubReposSource) DefaultInterval() time.Duration {
+	return time.Hour
+}
+
+// readSnapshots reads the snapshots of the counts of the repositories from
+// the stats file. If the file does not exist, there are no snapshots yet.
+func readSnapshots(file string) (map[string][]repoSnapshot, error) {
+	snapshots := map[string][]repoSnapshot{}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return snapshots, nil
+		}
+		return nil, fmt.Errorf("reading stats file %q failed: %v", file, err)
+	}
+
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return nil, fmt.Errorf("parsing stats file %q failed: %v", file, err)
+	}
+
+	return snapshots, nil
+}
+
+// writeSnapshots writes the snapshots of the counts of the repositories to
+// the stats file.
+func writeSnapshots(file string, snapshots map[string][]repoSnapshot) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return fmt.Errorf("creating stats directory %q failed: %v", filepath.Dir(file), err)
+	}
+
+	data, err := json.Marshal(snapshots)
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(file, data, 0600); err != nil {
+		return fmt.Errorf("writing stats file %q failed: %v", file, err)
+	}
+
+	return nil
+}
+
+// Fetch implements Source.
+func (s *githubReposSource) Fetch(ctx context.Context) (interface{}, error) {
+	data := []githubReposData{}
+	today := time.Now().Format("2006-01-02")
+
+	for _, owner := range s.settings.Owners {
+		d := githubReposData{owner: owner.Name}
+
+		repos, err := listGitHubRepos(ctx, s.client, s.settings.githubAPI, owner)
+		if err != nil {
+			return nil, err
+		}
+
+		stats := make([]githubRepoStats, len(repos))
+		for i, repo := range repos {
+			stats[i] = githubRepoStats{
+				repo: repo.GetName(),
+				counts: repoSnapshot{
+					Date:       today,
+					Stars:      repo.GetStargazersCount(),
+					Forks:      repo.GetForksCount(),
+					OpenIssues: repo.GetOpenIssuesCount(),
+				},
+				traffic: s.settings.Traffic && repo.GetPermissions()["admin"],
+			}
+			stats[i].deltas = s.snapshot(repo.GetFullName(), stats[i].counts)
+		}
+
+		// Get the traffic of the repositories we admin.
+		err = forEachGitHubRepo(ctx, repos, s.settings.Concurrency, func(i int, repo *github.Repository) error {
+			if !stats[i].traffic {
+				return nil
+			}
+			return s.getTraffic(ctx, owner.Name, repo, &stats[i])
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// Show the most starred repositories first.
+		sort.SliceStable(stats, func(i, j int) bool {
+			return stats[i].counts.Stars > stats[j].counts.Stars
+		})
+		d.repos = stats
+
+		data = append(data, d)
+	}
+
+	// Save the snapshots for tomorrow. The counts are still worth showing if
+	// this fails.
+	if err := writeSnapshots(s.statsFile, s.snapshots); err != nil {
+		logrus.Warn(err)
+	}
+
+	return data, nil
+}
+
+// snapshot records the counts of the repository for today and returns how
+// much they changed since the last snapshot before today, or nil if there is
+// none.
+func (s *githubReposSource) snapshot(repo string, counts repoSnapshot) *repoSnapshot {
+	snapshots := s.snapshots[repo]
+
+	// Replace the snapshot for today, we want the latest counts.
+	if n := len(snapshots); n > 0 && snapshots[n-1].Date == counts.Date {
+		snapshots = snapshots[:n-1]
+	}
+
+	var deltas *repoSnapshot
+	if n := len(snapshots); n > 0 {
+		last := snapshots[n-1]
+		deltas = &repoSnapshot{
+			Date:       last.Date,
+			Stars:      counts.Stars - last.Stars,
+			Forks:      counts.Forks - last.Forks,
+			OpenIssues: counts.OpenIssues - last.OpenIssues,
+		}
+	}
+
+	snapshots = append(snapshots, counts)
+	if len(snapshots) > keepSnapshots {
+		snapshots = snapshots[len(snapshots)-keepSnapshots:]
+	}
+	s.snapshots[repo] = snapshots
+
+	return deltas
+}
+
+// getTraffic gets the views, clones and top referrers over the last 14 days
+// for the repository.
+func (s *githubReposSource) getTraffic(ctx context.Context, owner string, repo *github.Repository, stats *githubRepoStats) error {
+	opt := &github.TrafficBreakdownOptions{Per: "day"}
+
+	views, _, err := s.client.Repositories.ListTrafficViews(ctx, owner, repo.GetName(), opt)
+	if err != nil {
+		return fmt.Errorf("getting views for %q failed: %v", repo.GetFullName(), err)
+	}
+	stats.views = trafficPerDay(views.Views)
+	stats.viewsSum = views.GetCount()
+
+	clones, _, err := s.client.Repositories.ListTrafficClones(ctx, owner, repo.GetName(), opt)
+	if err != nil {
+		return fmt.Errorf("getting clones for %q failed: %v", repo.GetFullName(), err)
+	}
+	stats.clones = trafficPerDay(clones.Clones)
+	stats.clonesSum = clones.GetCount()
+
+	stats.referrers, _, err = s.client.Repositories.ListTrafficReferrers(ctx, owner, repo.GetName())
+	if err != nil {
+		return fmt.Errorf("getting referrers for %q failed: %v", repo.GetFullName(), err)
+	}
+
+	return nil
+}
+
+// trafficPerDay returns the counts for each of the last 14 days, oldest
+// first. GitHub leaves out the days without traffic.
+func trafficPerDay(data []*github.TrafficData) []int {
+	counts := make([]int, trafficDays)
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for _, d := range data {
+		days := int(today.Sub(d.GetTimestamp().UTC().Truncate(24*time.Hour)).Hours() / 24)
+		if days >= 0 && days < trafficDays {
+			counts[trafficDays-1-days] = d.GetCount()
+		}
+	}
+	return counts
+}
+
+// printCount returns the count along with how much it changed, if it did.
+func printCount(count, delta int) string {
+	if delta == 0 {
+		return fmt.Sprintf("%d", count)
+	}
+	return fmt.Sprintf("%d (%+d)", count, delta)
+}
+
+// printSince describes the day the changes in the counts are since.
+func printSince(date string) string {
+	t, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return date
+	}
+	if t.Format("2006-01-02") == time.Now().AddDate(0, 0, -1).Format("2006-01-02") {
+		return "yesterday"
+	}
+	return t.Format("Mon, Jan 02")
+}
+
+// Render implements Source.
+func (s *githubReposSource) Render(v interface{}) []*termui.Row {
+	rows := []*termui.Row{}
+	tables := []termui.GridBufferer{}
+
+	for _, d := range v.([]githubReposData) {
+		// Initialize the table.
+		table := termui.NewTable()
+		trows := [][]string{
+			{"repo", "stars", "forks", "open issues", "views", "clones"},
+		}
+		greenrows := []int{}
+		since := ""
+
+		for _, repo := range d.repos {
+			views, clones := "", ""
+			if repo.traffic {
+				views = fmt.Sprintf("%d", repo.viewsSum)
+				clones = fmt.Sprintf("%d", repo.clonesSum)
+			}
+
+			var stars, forks, issues int
+			if repo.deltas != nil {
+				stars, forks, issues = repo.deltas.Stars, repo.deltas.Forks, repo.deltas.OpenIssues
+				since = repo.deltas.Date
+			}
+			trows = append(trows, []string{
+				repo.repo,
+				printCount(repo.counts.Stars, stars),
+				printCount(repo.counts.Forks, forks),
+				printCount(repo.counts.OpenIssues, issues),
+				views,
+				clones,
+			})
+
+			if stars > 0 {
+				greenrows = append(greenrows, len(trows)-1)
+			}
+		}
+
+		if len(trows) <= 1 {
+			// continue early if we have no data
+			continue
+		}
+
+		// Set the rows.
+		table.Rows = trows
+
+		// Set the default colors and settings.
+		table.FgColor = termui.ColorWhite
+		table.BgColor = termui.ColorDefault
+		table.TextAlign = termui.AlignLeft
+		table.Border = true
+		table.Block.BorderLabel = "GitHub repos for " + d.owner
+		if len(since) > 0 {
+			table.Block.BorderLabel += ", changes since " + printSince(since)
+		}
+		table.Analysis()
+		table.SetSize()
+		// Set the color to green for the repos that gained stars
+		for _, br := range greenrows {
+			table.FgColors[br] = termui.ColorGreen
+		}
+
+		tables = append(tables, table)
+
+		// Add the traffic below the tables.
+		if traffic := renderTraffic(d, s.settings.TrafficTop); traffic != nil {
+			rows = append(rows, traffic)
+		}
+	}
+
+	if len(tables) <= 0 {
+		return nil
+	}
+
+	return append(columnRows(tables), rows...)
+}
+
+// renderTraffic returns a row with sparklines of the daily views and clones
+// of the top most viewed repositories of the owner with traffic, along with
+// their top referrers. It returns nil if none of them had traffic.
+func renderTraffic(d githubReposData, top int) *termui.Row {
+	lines := []termui.Sparkline{}
+	type referrer struct {
+		repo string
+		*github.TrafficReferrer
+	}
+	referrers := []referrer{}
+
+	repos := []githubRepoStats{}
+	for _, repo := range d.repos {
+		if repo.traffic && repo.viewsSum+repo.clonesSum > 0 {
+			repos = append(repos, repo)
+		}
+	}
+	sort.SliceStable(repos, func(i, j int) bool {
+		return repos[i].viewsSum > repos[j].viewsSum
+	})
+	label := fmt.Sprintf("Traffic for %s over the last %d days", d.owner, trafficDays)
+	if len(repos) > top {
+		label = fmt.Sprintf("Traffic for the top %d of %d repos of %s over the last %d days", top, len(repos), d.owner, trafficDays)
+		repos = repos[:top]
+	}
+
+	for _, repo := range repos {
+		views := termui.NewSparkline()
+		views.Data = repo.views
+		views.Height = 2
+		views.LineColor = termui.ColorCyan
+		views.Title = fmt.Sprintf("%s: %d views", repo.repo, repo.viewsSum)
+
+		clones := termui.NewSparkline()
+		clones.Data = repo.clones
+		clones.Height = 2
+		clones.LineColor = termui.ColorMagenta
+		clones.Title = fmt.Sprintf("%s: %d clones", repo.repo, repo.clonesSum)
+
+		lines = append(lines, views, clones)
+
+		for _, r := range repo.referrers {
+			referrers = append(referrers, referrer{repo: repo.repo, TrafficReferrer: r})
+		}
+	}
+	if len(lines) <= 0 {
+		return nil
+	}
+
+	sparklines := termui.NewSparklines(lines...)
+	sparklines.BorderLabel = label
+	sparklines.Height = 3*len(lines) + 2
+
+	// Show the top referrers across the repositories.
+	sort.SliceStable(referrers, func(i, j int) bool {
+		return referrers[i].GetCount() > referrers[j].GetCount()
+	})
+	items := []string{}
+	for _, r := range referrers {
+		if len(items) >= sparklines.Height-2 {
+			break
+		}
+		items = append(items, fmt.Sprintf("%s: %s %d (%d unique)", r.repo, r.GetReferrer(), r.GetCount(), r.GetUniques()))
+	}
+
+	list := termui.NewList()
+	list.Items = items
+	list.ItemFgColor = termui.ColorWhite
+	list.BorderLabel = "Top referrers for " + d.owner
+	list.Height = sparklines.Height
+
+	return termui.NewRow(termui.NewCol(8, 0, sparklines), termui.NewCol(4, 0, list))
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+)
+
+func TestTrafficPerDay(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	day := func(daysAgo, count int) *github.TrafficData {
+		return &github.TrafficData{
+			Timestamp: &github.Timestamp{Time: today.AddDate(0, 0, -daysAgo)},
+			Count:     github.Int(count),
+		}
+	}
+
+	testCases := []struct {
+		name string
+		data []*github.TrafficData
+		want []int
+	}{
+		{
+			name: "no traffic",
+			want: []int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name: "every day",
+			data: []*github.TrafficData{
+				day(13, 1), day(12, 2), day(11, 3), day(10, 4), day(9, 5), day(8, 6), day(7, 7),
+				day(6, 8), day(5, 9), day(4, 10), day(3, 11), day(2, 12), day(1, 13), day(0, 14),
+			},
+			want: []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14},
+		},
+		{
+			name: "missing days",
+			data: []*github.TrafficData{day(13, 4), day(6, 2), day(0, 7)},
+			want: []int{4, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0, 0, 0, 7},
+		},
+		{
+			name: "out of range",
+			data: []*github.TrafficData{day(14, 9), day(5, 3), day(-1, 9)},
+			want: []int{0, 0, 0, 0, 0, 0, 0, 0, 3, 0, 0, 0, 0, 0},
+		},
+		{
+			name: "later in the day",
+			data: []*github.TrafficData{
+				{Timestamp: &github.Timestamp{Time: today.AddDate(0, 0, -1).Add(23 * time.Hour)}, Count: github.Int(5)},
+			},
+			want: []int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 5, 0},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := trafficPerDay(tc.data); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
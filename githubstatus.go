@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/gizak/termui"
+	"github.com/google/go-github/v32/github"
+)
+
+func init() {
+	registerSource("githubstatus", func() Source { return &githubStatusSource{} })
+}
+
+// githubStatus holds the commit statuses and check runs for the head of the
+// default branch of a repository.
+type githubStatus struct {
+	repo   string
+	branch string
+	sha    string
+	// contexts holds the state of each status context and check app by
+	// its name, ie. continuous-integration/travis-ci or GitHub Actions.
+	contexts map[string]string
+}
+
+// state returns the combined state of all the contexts.
+func (s githubStatus) state() string {
+	return combinedState(s.contexts)
+}
+
+// githubStatusData holds the statuses of the repositories of an owner.
+type githubStatusData struct {
+	owner string
+	// contexts holds the names of the contexts of all the repositories,
+	// sorted, so each one gets a column.
+	contexts []string
+	statuses []githubStatus
+}
+
+// githubStatusSettings holds the settings for a GitHub status data source.
+type githubStatusSettings struct {
+	githubRepoSettings `yaml:",inline"`
+}
+
+// githubStatusSource is a Source for the combined commit status and check
+// runs of the default branch of GitHub repositories, whichever CI systems
+// report them.
+type githubStatusSource struct {
+	settings githubStatusSettings
+	client   *github.Client
+}
+
+// Name implements Source.
+func (s *githubStatusSource) Name() string {
+	return "GitHub status"
+}
+
+// Configure implements Source.
+func (s *githubStatusSource) Configure(cfg *sourceConfig) (bool, error) {
+	// Get the settings from the flags and config file.
+	s.settings = githubStatusSettings{
+		githubRepoSettings: githubRepoSettingsFromFlags(),
+	}
+	if err := cfg.decode(&s.settings); err != nil {
+		return false, err
+	}
+
+	var (
+		ok  bool
+		err error
+	)
+	s.client, ok, err = s.settings.configure()
+	if !ok || err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Fetch implements Source.
+func (s *githubStatusSource) Fetch(ctx context.Context) (interface{}, error) {
+	data := []githubStatusData{}
+
+	for _, owner := range s.settings.Owners {
+		d := githubStatusData{owner: owner.Name}
+
+		repos, err := listGitHubRepos(ctx, s.client, s.settings.githubAPI, owner)
+		if err != nil {
+			return nil, err
+		}
+
+		statuses := make([]*githubStatus, len(repos))
+		err = forEachGitHubRepo(ctx, repos, s.settings.Concurrency, func(i int, repo *github.Repository) error {
+			status, err := s.getStatus(ctx, owner.Name, repo)
+			statuses[i] = status
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// Add the statuses in the same order as the repositories, along
+		// with the names of their contexts.
+		seen := map[string]bool{}
+		for _, status := range statuses {
+			if status == nil {
+				continue
+			}
+			for name := range status.contexts {
+				if !seen[name] {
+					seen[name] = true
+					d.contexts = append(d.contexts, name)
+				}
+			}
+			d.statuses = append(d.statuses, *status)
+		}
+		sort.Strings(d.contexts)
+
+		data = append(data, d)
+	}
+
+	return data, nil
+}
+
+// getStatus returns the statuses for the head of the default branch of the
+// repository. It returns nil if nothing reports a status for it.
+func (s *githubStatusSource) getStatus(ctx context.Context, owner string, repo *github.Repository) (*githubStatus, error) {
+	branch := repo.GetDefaultBranch()
+	sha, contexts, err := getCommitContexts(ctx, s.client, owner, repo, branch)
+	if err != nil {
+		// Empty repos have no default branch, so we might as well error
+		// silently if we get a 404.
+		if isGitHubNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(contexts) <= 0 {
+		return nil, nil
+	}
+
+	return &githubStatus{
+		repo:     repo.GetName(),
+		branch:   branch,
+		sha:      sha,
+		contexts: contexts,
+	}, nil
+}
+
+// getCommitContexts returns the SHA of the commit the ref points to, along
+// with the state of each of the commit status contexts and check apps for
+// it by their names.
+func getCommitContexts(ctx context.Context, client *github.Client, owner string, repo *github.Repository, ref string) (string, map[string]string, error) {
+	contexts := map[string]string{}
+
+	// Get the commit statuses, ie. from Travis CI or Jenkins.
+	combined, _, err := client.Repositories.GetCombinedStatus(ctx, owner, repo.GetName(), ref, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return "", nil, fmt.Errorf("getting combined status of %s for %q failed: %w", ref, repo.GetFullName(), err)
+	}
+	for _, st := range combined.Statuses {
+		contexts[st.GetContext()] = st.GetState()
+	}
+
+	// Get the check runs, ie. from GitHub Actions. An app can have many
+	// check suites for a commit, ie. one for each workflow, so the state
+	// for the app is the worst state of its check runs. Getting the runs
+	// rather than the suites also skips the suites of apps that are
+	// installed but never ran, which stay queued forever.
+	checks := map[string]string{}
+	opt := &github.ListCheckRunsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		runs, resp, err := client.Checks.ListCheckRunsForRef(ctx, owner, repo.GetName(), ref, opt)
+		if err != nil {
+			return "", nil, fmt.Errorf("listing check runs of %s for %q failed: %w", ref, repo.GetFullName(), err)
+		}
+		for _, run := range runs.CheckRuns {
+			state := run.GetStatus()
+			if state == "completed" {
+				state = run.GetConclusion()
+			}
+			name := run.GetApp().GetName()
+			if prev, ok := checks[name]; !ok || stateRank(state) > stateRank(prev) {
+				checks[name] = state
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	for name, state := range checks {
+		contexts[name] = state
+	}
+
+	return combined.GetSHA(), contexts, nil
+}
+
+// combinedState returns the combined state of the contexts: failure if any
+// of them failed, pending if any of them has not finished, otherwise
+// success.
+func combinedState(contexts map[string]string) string {
+	state := "success"
+	for _, c := range contexts {
+		switch contextColor(c) {
+		case "red":
+			return "failure"
+		case "yellow":
+			state = "pending"
+		}
+	}
+	return state
+}
+
+// stateRank returns how bad the state of a status context or check run is,
+// so the worst of many can be shown: failed is worse than not finished,
+// which is worse than passed.
+func stateRank(state string) int {
+	switch contextColor(state) {
+	case "red":
+		return 2
+	case "yellow":
+		return 1
+	}
+	return 0
+}
+
+// contextColor returns the color for the state of a status context or check
+// run.
+func contextColor(state string) string {
+	switch state {
+	case "success", "neutral", "skipped":
+		return "green"
+	case "failure", "error", "timed_out", "startup_failure", "action_required":
+		return "red"
+	}
+	// The context is pending, queued, in progress, cancelled or stale.
+	return "yellow"
+}
+
+// Render implements Source.
+func (s *githubStatusSource) Render(v interface{}) []*termui.Row {
+	tables := []termui.GridBufferer{}
+
+	for _, d := range v.([]githubStatusData) {
+		// Initialize the table.
+		table := termui.NewTable()
+		rows := [][]string{
+			append([]string{"repo", "branch", "commit"}, d.contexts...),
+		}
+		redrows := []int{}
+		otherrows := []int{}
+
+		for _, status := range d.statuses {
+			state := status.state()
+			if showAllBuilds || state != "success" {
+				row := []string{status.repo, status.branch, printCommit(status.sha, "")}
+				for _, name := range d.contexts {
+					cell := ""
+					if c, ok := status.contexts[name]; ok {
+						cell = fmt.Sprintf("[%s](fg-%s)", c, contextColor(c))
+					}
+					row = append(row, cell)
+				}
+				rows = append(rows, row)
+
+				if state == "failure" {
+					redrows = append(redrows, len(rows)-1)
+				} else if state != "success" {
+					otherrows = append(otherrows, len(rows)-1)
+				}
+			}
+		}
+
+		if len(rows) <= 1 {
+			// continue early if we have no data
+			continue
+		}
+
+		// Set the rows.
+		table.Rows = rows
+
+		// Set the default colors and settings.
+		table.FgColor = termui.ColorWhite
+		table.BgColor = termui.ColorDefault
+		table.TextAlign = termui.AlignLeft
+		table.Border = true
+		table.Separator = true
+		table.Block.BorderLabel = "GitHub status of the default branches for " + d.owner
+		table.Analysis()
+		table.SetSize()
+		// Set the color to red for the red rows
+		for _, br := range redrows {
+			table.FgColors[br] = termui.ColorRed
+		}
+		// Set the color to yellow for the other rows
+		for _, br := range otherrows {
+			table.FgColors[br] = termui.ColorYellow
+		}
+
+		tables = append(tables, table)
+	}
+
+	return columnRows(tables)
+}
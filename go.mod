@@ -4,26 +4,21 @@ go 1.14
 
 require (
 	cloud.google.com/go v0.25.0 // indirect
-	github.com/Ableton/go-travis v0.0.0-20170814145453-f849ce748f41
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/fatih/structs v1.0.0 // indirect
 	github.com/genuinetools/pkg v0.0.0-20180716210454-965f911b80a9
 	github.com/gizak/termui v2.2.0+incompatible
-	github.com/google/go-github v0.0.0-20180716180158-c0b63e2f9bb1
-	github.com/google/go-querystring v0.0.0-20170111101155-53e6ce116135 // indirect
+	github.com/google/go-github/v32 v32.1.0
 	github.com/maruel/panicparse v1.1.1 // indirect
 	github.com/mattn/go-runewidth v0.0.2 // indirect
 	github.com/mitchellh/go-wordwrap v0.0.0-20150314170334-ad45545899c7 // indirect
 	github.com/nsf/termbox-go v0.0.0-20180613055208-5c94acc5e6eb // indirect
-	github.com/oleiade/reflections v1.0.0 // indirect
 	github.com/onsi/gomega v1.4.2 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/sirupsen/logrus v1.0.5
 	github.com/stretchr/testify v1.2.2 // indirect
-	golang.org/x/crypto v0.0.0-20180621125126-a49355c7e3f8 // indirect
-	golang.org/x/oauth2 v0.0.0-20180620175406-ef147856a6dd
+	golang.org/x/oauth2 v0.0.0-20180821212333-d2e6202438be
 	google.golang.org/api v0.0.0-20180716222000-81e9282165ac
-	google.golang.org/appengine v1.1.0 // indirect
 	gopkg.in/airbrake/gobrake.v2 v2.0.9 // indirect
 	gopkg.in/gemnasium/logrus-airbrake-hook.v2 v2.1.2 // indirect
+	gopkg.in/yaml.v2 v2.4.0
 )
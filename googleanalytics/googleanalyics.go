@@ -40,7 +40,9 @@ type Client struct {
 // To create a service account client, click "Create new Client ID",
 // select "Service Account", and click "Create Client ID". A JSON
 // key file will then be downloaded to your computer.
-func New(keyfile string, debug bool) (*Client, error) {
+// The requests are made with the given HTTP client, which is wrapped
+// with the OAuth2 transport.
+func New(keyfile string, httpClient *http.Client) (*Client, error) {
 	// Read the keyfile.
 	data, err := ioutil.ReadFile(keyfile)
 	if err != nil {
@@ -58,16 +60,9 @@ func New(keyfile string, debug bool) (*Client, error) {
 
 	// The following GET request will be authorized and authenticated
 	// on the behalf of your service account.
-	if debug {
-		ctx := context.WithValue(
-			context.Background(),
-			oauth2.HTTPClient,
-			&http.Client{Transport: &logTransport{http.DefaultTransport}},
-		)
-		client.client = client.config.Client(ctx)
-	} else {
-		client.client = client.config.Client(context.Background())
-	}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
+	client.client = client.config.Client(ctx)
+	client.client.Timeout = httpClient.Timeout
 
 	// Construct the analytics reporting v4 service object.
 	client.service, err = ga.New(client.client)
@@ -1,62 +1,521 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gizak/termui"
 	"github.com/jessfraz/tdash/jenkins"
+	"github.com/jessfraz/tdash/transport"
 	"github.com/sirupsen/logrus"
 )
 
-func doJenkinsCI() (*termui.Table, error) {
-	// Check that the jenkins base URI is not empty.
-	if len(jenkinsBaseURI) <= 0 {
-		logrus.Warn("Jenkins Base URI cannot be empty")
-		logrus.Info("skipping Jenkins CI data")
-		return nil, nil
+func init() {
+	registerSource("jenkins", func() Source { return &jenkinsSource{} })
+}
+
+// jenkinsInstance holds the settings for a Jenkins instance.
+type jenkinsInstance struct {
+	// Name is the label for the instance, it defaults to the URI.
+	Name     string `yaml:"name"`
+	URI      string `yaml:"uri"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// PasswordEnv is the name of an environment variable holding the
+	// password or API token for the instance.
+	PasswordEnv string `yaml:"password_env"`
+	// PasswordFile is the path to a file holding the password or API token
+	// for the instance.
+	PasswordFile string `yaml:"password_file"`
+
+	jenkinsTLS `yaml:",inline"`
+}
+
+// jenkinsTLS holds the TLS settings for a Jenkins instance behind a private
+// CA or that requires a client certificate.
+type jenkinsTLS struct {
+	CAFile   string `yaml:"ca_file"`
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// InsecureSkipVerify turns off verification of the server certificate,
+	// it should only be used for test instances.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+}
+
+// jenkinsSettings holds the settings for a Jenkins CI data source.
+// If no instances are given, a single instance is configured from the uri,
+// username and password.
+type jenkinsSettings struct {
+	URI       string            `yaml:"uri"`
+	Username  string            `yaml:"username"`
+	Password  string            `yaml:"password"`
+	Instances []jenkinsInstance `yaml:"instances"`
+
+	jenkinsTLS `yaml:",inline"`
+
+	// Depth is how many levels of folders and multibranch projects to
+	// descend into.
+	Depth int `yaml:"depth"`
+	// Include and Exclude are glob patterns matched against the full path
+	// of the jobs, ie. folder/job/branch.
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+	// History is the number of recent builds to show for each job, along
+	// with how flaky they are. Zero turns it off.
+	History int `yaml:"history"`
+	// Queue shows the build queue and executors for each instance.
+	Queue bool `yaml:"queue"`
+	// Stages shows the stage that failed or is running for Pipeline jobs,
+	// along with how long each stage took.
+	Stages bool `yaml:"stages"`
+	// Tests shows the test results for unstable and failed builds.
+	Tests bool `yaml:"tests"`
+	// Failures is the number of failing test cases to show in the detail
+	// pane for a job.
+	Failures int `yaml:"failures"`
+	// Actions turns on rebuilding, building and aborting the selected job.
+	Actions bool `yaml:"actions"`
+	// Concurrency is the number of jobs to get the stages and test results
+	// for at once.
+	Concurrency int `yaml:"concurrency"`
+}
+
+// jenkinsClient holds the client for a Jenkins instance.
+type jenkinsClient struct {
+	name   string
+	client *jenkins.Client
+}
+
+// jenkinsData holds the jobs for a Jenkins instance, and the build queue and
+// nodes if they were fetched.
+type jenkinsData struct {
+	name   string
+	client *jenkins.Client
+	jobs   []jenkins.Job
+	// runs holds the stages for the last build of the Pipeline jobs by
+	// the job URL.
+	runs map[string]jenkins.Run
+	// reports holds the test reports for the last build of the unstable
+	// and failed jobs by the job URL.
+	reports   map[string]jenkins.TestReport
+	queue     []jenkins.QueueItem
+	computers []jenkins.Computer
+	// err is why the instance could not be fetched. The rest of the data
+	// is then from the last time it was.
+	err error
+}
+
+// jenkinsSource is a Source for Jenkins CI builds.
+type jenkinsSource struct {
+	settings jenkinsSettings
+	clients  []jenkinsClient
+
+	// mu guards last, which holds the data from the last successful fetch
+	// of each instance.
+	mu   sync.Mutex
+	last []jenkinsData
+}
+
+// Name implements Source.
+func (s *jenkinsSource) Name() string {
+	return "Jenkins CI"
+}
+
+// Configure implements Source.
+func (s *jenkinsSource) Configure(cfg *sourceConfig) (bool, error) {
+	// Get the settings from the flags and config file.
+	s.settings = jenkinsSettings{
+		URI:         jenkinsBaseURI,
+		Username:    jenkinsUsername,
+		Password:    jenkinsPassword,
+		Depth:       3,
+		History:     10,
+		Stages:      true,
+		Tests:       true,
+		Failures:    10,
+		Concurrency: 8,
+	}
+	if err := cfg.decode(&s.settings); err != nil {
+		return false, err
+	}
+	if flagPassed("jenkins-uri") {
+		s.settings.URI = jenkinsBaseURI
+	}
+	if flagPassed("jenkins-username") {
+		s.settings.Username = jenkinsUsername
+	}
+	if flagPassed("jenkins-password") {
+		s.settings.Password = jenkinsPassword
+	}
+	if s.settings.Concurrency <= 0 {
+		s.settings.Concurrency = 1
+	}
+
+	instances := s.settings.Instances
+	if len(instances) <= 0 {
+		// Check that the jenkins base URI is not empty.
+		if len(s.settings.URI) <= 0 {
+			logrus.Warn("Jenkins Base URI cannot be empty")
+			return false, nil
+		}
+
+		instances = []jenkinsInstance{{
+			URI:        s.settings.URI,
+			Username:   s.settings.Username,
+			Password:   s.settings.Password,
+			jenkinsTLS: s.settings.jenkinsTLS,
+		}}
+	}
+
+	s.clients = []jenkinsClient{}
+	for i, instance := range instances {
+		if len(instance.URI) <= 0 {
+			return false, fmt.Errorf("jenkins instance %d is missing a uri", i)
+		}
+		if len(instance.Name) <= 0 {
+			instance.Name = instance.URI
+		}
+
+		// Get the password for the instance.
+		password, err := instance.password()
+		if err != nil {
+			return false, err
+		}
+
+		// Check that the jenkins password is not empty if we have a username.
+		// Instances without a username are accessed anonymously.
+		if len(instance.Username) > 0 && len(password) <= 0 {
+			return false, fmt.Errorf("jenkins password for %q cannot be empty", instance.Name)
+		}
+
+		client, err := instance.httpClient()
+		if err != nil {
+			return false, err
+		}
+
+		// Initialize the jenkins api client
+		s.clients = append(s.clients, jenkinsClient{
+			name:   instance.Name,
+			client: jenkins.New(instance.URI, instance.Username, password, client),
+		})
 	}
 
-	// Check that the jenkins username is not empty.
-	if len(jenkinsUsername) <= 0 {
-		logrus.Warn("Jenkins username cannot be empty")
-		logrus.Info("skipping Jenkins CI data")
-		return nil, nil
+	return true, nil
+}
+
+// password returns the password for the Jenkins instance from the settings,
+// the environment variable or the file, in that order.
+func (i jenkinsInstance) password() (string, error) {
+	if len(i.Password) > 0 {
+		return i.Password, nil
 	}
 
-	// Check that the jenkins password is not empty.
-	if len(jenkinsPassword) <= 0 {
-		logrus.Warn("Jenkins password cannot be empty")
-		logrus.Info("skipping Jenkins CI data")
-		return nil, nil
+	if len(i.PasswordEnv) > 0 {
+		return os.Getenv(i.PasswordEnv), nil
 	}
 
-	// Initialize the jenkins api client
-	jenkinsClient := jenkins.New(jenkinsBaseURI, jenkinsUsername, jenkinsPassword)
+	if len(i.PasswordFile) > 0 {
+		b, err := ioutil.ReadFile(expandPath(i.PasswordFile))
+		if err != nil {
+			return "", fmt.Errorf("reading jenkins password file for %q failed: %v", i.Name, err)
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+
+	return "", nil
+}
 
-	// Get all the jobs
-	jobs, err := jenkinsClient.GetJobs()
+// httpClient returns the HTTP client for the Jenkins instance. Instances with
+// TLS settings get a client of their own, the rest share httpClient.
+func (i jenkinsInstance) httpClient() (*http.Client, error) {
+	opts := transport.TLSOptions{
+		CAFile:             expandPath(i.CAFile),
+		CertFile:           expandPath(i.CertFile),
+		KeyFile:            expandPath(i.KeyFile),
+		InsecureSkipVerify: i.InsecureSkipVerify,
+	}
+	if opts.IsZero() {
+		return httpClient, nil
+	}
+
+	base, err := transport.NewTLSTransport(opts)
 	if err != nil {
-		return nil, fmt.Errorf("getting all jenkins jobs failed: %v", err)
+		return nil, fmt.Errorf("setting up TLS for jenkins instance %q failed: %v", i.Name, err)
+	}
+	if i.InsecureSkipVerify {
+		logrus.Warnf("not verifying the TLS certificate for jenkins instance %q", i.Name)
+	}
+
+	clientOpts := httpOptions
+	clientOpts.Base = base
+	return transport.NewClient(clientOpts), nil
+}
+
+// Fetch implements Source.
+func (s *jenkinsSource) Fetch(ctx context.Context) (interface{}, error) {
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, len(s.clients))
+		data = make([]jenkinsData, len(s.clients))
+	)
+
+	// Get all the jobs for each instance at once.
+	for i, c := range s.clients {
+		wg.Add(1)
+		go func(i int, c jenkinsClient) {
+			defer wg.Done()
+
+			jobs, err := c.client.GetJobs(ctx, s.settings.Depth, s.settings.History)
+			if err != nil {
+				errs[i] = fmt.Errorf("getting all jenkins jobs for %q failed: %v", c.name, err)
+				return
+			}
+
+			// Filter the jobs by their path.
+			d := jenkinsData{name: c.name, client: c.client}
+			for _, job := range jobs {
+				if matchPatterns(s.settings.Include, s.settings.Exclude, job.Path) {
+					d.jobs = append(d.jobs, job)
+				}
+			}
+
+			if s.settings.Stages || s.settings.Tests {
+				if err := s.getBuildDetails(ctx, c, &d); err != nil {
+					errs[i] = err
+					return
+				}
+			}
+
+			if s.settings.Queue {
+				d.queue, err = c.client.GetQueue(ctx)
+				if err != nil {
+					errs[i] = fmt.Errorf("getting jenkins build queue for %q failed: %v", c.name, err)
+					return
+				}
+
+				d.computers, err = c.client.GetComputers(ctx)
+				if err != nil {
+					errs[i] = fmt.Errorf("getting jenkins nodes for %q failed: %v", c.name, err)
+					return
+				}
+			}
+
+			data[i] = d
+		}(i, c)
+	}
+	wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.last) != len(s.clients) {
+		s.last = make([]jenkinsData, len(s.clients))
+	}
+
+	failed := []string{}
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 && len(failed) == len(s.clients) {
+		return nil, errors.New(strings.Join(failed, "; "))
+	}
+
+	// Keep showing the instances that failed with their data from the last
+	// time they were fetched.
+	for i, err := range errs {
+		if err == nil {
+			s.last[i] = data[i]
+			continue
+		}
+
+		logrus.Warn(err)
+		data[i] = s.last[i]
+		data[i].name = s.clients[i].name
+		data[i].client = s.clients[i].client
+		data[i].err = err
+	}
+
+	return data, nil
+}
+
+// getBuildDetails gets the stages and test reports for the last build of the
+// jobs that are shown, at most s.settings.Concurrency jobs at a time. Jobs
+// without stages or test reports, ie. if the Pipeline Stage View plugin is not
+// installed or the build did not record any tests, are skipped.
+func (s *jenkinsSource) getBuildDetails(ctx context.Context, c jenkinsClient, d *jenkinsData) error {
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	d.runs = map[string]jenkins.Run{}
+	d.reports = map[string]jenkins.TestReport{}
+	sem := make(chan struct{}, s.settings.Concurrency)
+	for _, job := range d.jobs {
+		if len(job.LastBuild.URL) <= 0 || !showBuild(job.LastBuild) {
+			continue
+		}
+
+		getStages := s.settings.Stages && job.IsPipeline()
+		getTests := s.settings.Tests && (job.LastBuild.Result == "UNSTABLE" || job.LastBuild.Result == "FAILURE")
+		if !getStages && !getTests {
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+
+		wg.Add(1)
+		go func(job jenkins.Job) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if getStages {
+				run, err := c.client.GetStages(ctx, job.LastBuild)
+				if err != nil {
+					logrus.Debugf("getting jenkins stages for %q on %q failed: %v", job.Path, c.name, err)
+				} else {
+					mu.Lock()
+					d.runs[job.URL] = run
+					mu.Unlock()
+				}
+			}
+
+			if getTests {
+				report, err := c.client.GetTestReport(ctx, job.LastBuild)
+				if err != nil {
+					logrus.Debugf("getting jenkins test report for %q on %q failed: %v", job.Path, c.name, err)
+				} else {
+					mu.Lock()
+					d.reports[job.URL] = report
+					mu.Unlock()
+				}
+			}
+		}(job)
+	}
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+// showBuild returns true if the build should be shown in the table of jobs.
+func showBuild(build jenkins.Build) bool {
+	return showAllBuilds || build.Result != "SUCCESS"
+}
+
+// Render implements Source.
+func (s *jenkinsSource) Render(v interface{}) []*termui.Row {
+	data := v.([]jenkinsData)
+
+	tables := []termui.GridBufferer{}
+	for _, d := range data {
+		table := s.renderJobs(d)
+		if d.err == nil {
+			if table != nil {
+				tables = append(tables, table)
+			}
+			continue
+		}
+
+		// Show the error below the jobs from the last time the instance
+		// was fetched.
+		rows := []*termui.Row{}
+		if table != nil {
+			table.BorderFg = termui.ColorRed
+			rows = append(rows, termui.NewRow(termui.NewCol(12, 0, table)))
+		}
+		tables = append(tables, newStack(append(rows, jenkinsErrorRow(d))...))
 	}
+	rows := columnRows(tables)
+
+	if s.settings.Queue {
+		tables = []termui.GridBufferer{}
+		for _, d := range data {
+			queue, computers := renderQueue(d), renderComputers(d)
+			if d.err != nil {
+				queue.BorderFg = termui.ColorRed
+				computers.BorderFg = termui.ColorRed
+			}
+			tables = append(tables, queue, computers)
+		}
+		rows = append(rows, columnRows(tables)...)
+	}
+
+	return rows
+}
+
+// jenkinsErrorRow returns a row with the error fetching a Jenkins instance.
+func jenkinsErrorRow(d jenkinsData) *termui.Row {
+	p := termui.NewPar("error: " + d.err.Error())
+	p.TextFgColor = termui.ColorRed
+	p.BorderFg = termui.ColorRed
+	p.BorderLabel = "Jenkins builds for " + d.name
+	p.Height = 3
 
+	return termui.NewRow(termui.NewCol(12, 0, p))
+}
+
+// renderJobs returns the table of jobs for a Jenkins instance.
+// It returns nil if there are no jobs to show.
+func (s *jenkinsSource) renderJobs(d jenkinsData) *termui.Table {
 	// Initialize the table.
 	table := termui.NewTable()
 	rows := [][]string{
 		{"job", "state", "finished at"},
 	}
+	if s.settings.Stages {
+		rows[0] = append(rows[0], "stage", "stages")
+	}
+	if s.settings.Tests {
+		rows[0] = append(rows[0], "tests")
+	}
+	if s.settings.History > 0 {
+		rows[0] = append(rows[0], "history", "flaky")
+	}
 	redrows := []int{}
 	otherrows := []int{}
+	selected := selectedKey(s)
+	selectedRow := -1
 
 	// Iterate over the jobs.
-	for _, job := range jobs {
-		if job.LastBuild.Result == "" {
-			// Then the job is currently running.
-			job.LastBuild.Result = "RUNNING"
-		}
+	for _, job := range d.jobs {
+		if showBuild(job.LastBuild) {
+			if job.LastBuild.Result == "" {
+				// Then the job is currently running.
+				job.LastBuild.Result = "RUNNING"
+			}
 
-		if showAllBuilds || job.LastBuild.Result != "SUCCESS" {
-			rows = append(rows, []string{job.DisplayName, job.LastBuild.Result, time.Unix(0, int64(time.Millisecond)*job.LastBuild.Timestamp).Format(time.RFC3339)})
+			row := []string{job.Path, job.LastBuild.Result, time.Unix(0, int64(time.Millisecond)*job.LastBuild.Timestamp).Format(time.RFC3339)}
+			if s.settings.Stages {
+				run := d.runs[job.URL]
+				row = append(row, printStage(run), printStages(run))
+			}
+			if s.settings.Tests {
+				row = append(row, printTests(d.reports[job.URL]))
+			}
+			if s.settings.History > 0 {
+				row = append(row, printHistory(job.Builds), printFlakiness(job))
+			}
+			rows = append(rows, row)
+			if job.URL == selected {
+				selectedRow = len(rows) - 1
+			}
 			if job.LastBuild.Result == "FAILURE" {
 				redrows = append(redrows, len(rows)-1)
 			} else if job.LastBuild.Result != "SUCCESS" {
@@ -67,7 +526,7 @@ func doJenkinsCI() (*termui.Table, error) {
 
 	if len(rows) <= 1 {
 		// return early if we have no data
-		return nil, nil
+		return nil
 	}
 
 	// Set the rows.
@@ -78,7 +537,7 @@ func doJenkinsCI() (*termui.Table, error) {
 	table.BgColor = termui.ColorDefault
 	table.TextAlign = termui.AlignLeft
 	table.Border = true
-	table.Block.BorderLabel = "Jenkins builds for " + jenkinsBaseURI
+	table.Block.BorderLabel = "Jenkins builds for " + d.name
 	table.Analysis()
 	table.SetSize()
 	// Set the color to red for the red rows
@@ -89,6 +548,409 @@ func doJenkinsCI() (*termui.Table, error) {
 	for _, br := range otherrows {
 		table.FgColors[br] = termui.ColorYellow
 	}
+	if selectedRow > 0 {
+		highlightRow(table, selectedRow)
+	}
+
+	return table
+}
+
+// Items implements Selectable. The jobs are selected by their URL.
+func (s *jenkinsSource) Items(v interface{}) []string {
+	keys := []string{}
+	for _, d := range v.([]jenkinsData) {
+		for _, job := range d.jobs {
+			if showBuild(job.LastBuild) {
+				keys = append(keys, job.URL)
+			}
+		}
+	}
+	return keys
+}
+
+// Detail implements Selectable. It shows the last build of the job with its
+// stages and failing test cases.
+func (s *jenkinsSource) Detail(v interface{}, key string) []*termui.Row {
+	for _, d := range v.([]jenkinsData) {
+		for _, job := range d.jobs {
+			if job.URL != key {
+				continue
+			}
+
+			result := job.LastBuild.Result
+			if result == "" {
+				result = "RUNNING"
+			}
+
+			items := []string{
+				fmt.Sprintf("build #%d %s at %s", job.LastBuild.Number, result, time.Unix(0, int64(time.Millisecond)*job.LastBuild.Timestamp).Format(time.RFC3339)),
+				job.LastBuild.URL,
+			}
+			if run, ok := d.runs[job.URL]; ok {
+				if stage := printStage(run); len(stage) > 0 {
+					items = append(items, stage)
+				}
+				items = append(items, "stages: "+printStages(run))
+			}
+			if report, ok := d.reports[job.URL]; ok {
+				items = append(items, "tests: "+printTests(report))
+				for _, c := range report.FailedCases(s.settings.Failures) {
+					items = append(items, "  [FAIL] "+c.FullName())
+				}
+				if report.FailCount > s.settings.Failures {
+					items = append(items, fmt.Sprintf("  and %d more", report.FailCount-s.settings.Failures))
+				}
+			}
+
+			if len(job.Builds) > 0 {
+				items = append(items, fmt.Sprintf("history: %s flaky %s", printHistory(job.Builds), printFlakiness(job)))
+			}
+
+			keys := []string{}
+			if len(job.LastBuild.URL) > 0 {
+				keys = append(keys, "l log")
+			}
+			if s.settings.Actions {
+				for _, a := range s.jobActions(d, job) {
+					keys = append(keys, a.key+" "+strings.SplitN(a.name, " ", 2)[0])
+				}
+			}
+			if len(keys) > 0 {
+				items = append(items, "keys: "+strings.Join(keys, ", "))
+			}
+
+			list := termui.NewList()
+			list.Items = items
+			list.ItemFgColor = termui.ColorWhite
+			list.BorderLabel = fmt.Sprintf("%s on %s", job.Path, d.name)
+			list.Height = len(items) + 2
+			if result == "FAILURE" {
+				list.BorderFg = termui.ColorRed
+			} else if result != "SUCCESS" {
+				list.BorderFg = termui.ColorYellow
+			}
+
+			rows := []*termui.Row{termui.NewRow(termui.NewCol(12, 0, list))}
+			if sparklines := renderDurations(job.Builds); sparklines != nil {
+				rows = append(rows, termui.NewRow(termui.NewCol(12, 0, sparklines)))
+			}
+			return rows
+		}
+	}
+	return nil
+}
+
+// Log implements Logger. It streams the console output of the last build of
+// the job.
+func (s *jenkinsSource) Log(v interface{}, key string) (string, logStreamer) {
+	for _, d := range v.([]jenkinsData) {
+		for _, job := range d.jobs {
+			if job.URL != key || len(job.LastBuild.URL) <= 0 {
+				continue
+			}
+
+			client, build := d.client, job.LastBuild
+			title := fmt.Sprintf("Log for %s #%d on %s", job.Path, build.Number, d.name)
+			return title, func(ctx context.Context, w io.Writer) error {
+				return client.StreamLog(ctx, build, w)
+			}
+		}
+	}
+	return "", nil
+}
+
+// Actions implements Actioner. The selected job can be rebuilt with the
+// parameters of its last build, built with parameters from a form, or have
+// its running build aborted.
+func (s *jenkinsSource) Actions(v interface{}, key string) []action {
+	if !s.settings.Actions {
+		return nil
+	}
+
+	for _, d := range v.([]jenkinsData) {
+		for _, job := range d.jobs {
+			if job.URL == key {
+				return s.jobActions(d, job)
+			}
+		}
+	}
+	return nil
+}
+
+// jobActions returns the actions for a job.
+func (s *jenkinsSource) jobActions(d jenkinsData, job jenkins.Job) []action {
+	client := d.client
+	actions := []action{
+		{
+			key:  "r",
+			name: fmt.Sprintf("rebuild %s on %s", job.Path, d.name),
+			run: func(ctx context.Context, _ map[string]string) error {
+				return client.Rebuild(ctx, job)
+			},
+		},
+		{
+			key:  "b",
+			name: fmt.Sprintf("build %s on %s", job.Path, d.name),
+			form: func(ctx context.Context) ([]field, error) {
+				params, err := client.GetParameters(ctx, job)
+				if err != nil {
+					return nil, err
+				}
+
+				fields := []field{}
+				for _, p := range params {
+					fields = append(fields, field{
+						name:        p.Name,
+						description: p.Description,
+						value:       p.DefaultParameterValue.String(),
+						choices:     p.Choices,
+					})
+				}
+				return fields, nil
+			},
+			run: func(ctx context.Context, values map[string]string) error {
+				// Parameterized jobs are built with the parameters that
+				// were changed, the others get their defaults.
+				var params url.Values
+				if values != nil {
+					params = url.Values{}
+					for k, v := range values {
+						params.Set(k, v)
+					}
+				}
+				return client.Build(ctx, job, params)
+			},
+		},
+	}
+
+	if job.LastBuild.Result == "" && len(job.LastBuild.URL) > 0 {
+		// The last build is running.
+		actions = append(actions, action{
+			key:  "a",
+			name: fmt.Sprintf("abort %s #%d on %s", job.Path, job.LastBuild.Number, d.name),
+			run: func(ctx context.Context, _ map[string]string) error {
+				return client.Abort(ctx, job.LastBuild)
+			},
+		})
+	}
+
+	return actions
+}
+
+// renderQueue returns the table of items in the build queue for a Jenkins
+// instance, showing how long they have waited and why.
+func renderQueue(d jenkinsData) *termui.Table {
+	table := termui.NewTable()
+	rows := [][]string{
+		{"queued", "waiting", "why"},
+	}
+	redrows := []int{}
+	otherrows := []int{}
+
+	for _, item := range d.queue {
+		name := item.Task.FullName
+		if len(name) <= 0 {
+			name = item.Task.Name
+		}
+
+		waiting := time.Since(time.Unix(0, int64(time.Millisecond)*item.InQueueSince))
+		rows = append(rows, []string{name, printDuration(waiting), item.Why})
+		if item.Stuck {
+			redrows = append(redrows, len(rows)-1)
+		} else if item.Blocked {
+			otherrows = append(otherrows, len(rows)-1)
+		}
+	}
+
+	if len(rows) <= 1 {
+		rows = append(rows, []string{"nothing queued", "", ""})
+	}
+
+	// Set the rows.
+	table.Rows = rows
+
+	// Set the default colors and settings.
+	table.FgColor = termui.ColorWhite
+	table.BgColor = termui.ColorDefault
+	table.TextAlign = termui.AlignLeft
+	table.Border = true
+	table.Block.BorderLabel = fmt.Sprintf("Jenkins queue for %s (%d)", d.name, len(d.queue))
+	table.Analysis()
+	table.SetSize()
+	// Set the color to red for the stuck items
+	for _, br := range redrows {
+		table.FgColors[br] = termui.ColorRed
+	}
+	// Set the color to yellow for the blocked items
+	for _, br := range otherrows {
+		table.FgColors[br] = termui.ColorYellow
+	}
+
+	return table
+}
+
+// renderComputers returns the table of nodes for a Jenkins instance,
+// showing how many of their executors are busy and which are offline.
+func renderComputers(d jenkinsData) *termui.Table {
+	table := termui.NewTable()
+	rows := [][]string{
+		{"node", "busy", "status"},
+	}
+	redrows := []int{}
+	otherrows := []int{}
+
+	busy, total := 0, 0
+	for _, c := range d.computers {
+		status := "online"
+		if c.Offline {
+			status = "offline"
+			if c.TemporarilyOffline {
+				status = "temporarily offline"
+			}
+			if len(c.OfflineCauseReason) > 0 {
+				status += ": " + c.OfflineCauseReason
+			}
+		}
+
+		rows = append(rows, []string{c.DisplayName, fmt.Sprintf("%d/%d", c.BusyExecutors(), c.NumExecutors), status})
+		if c.Offline {
+			redrows = append(redrows, len(rows)-1)
+			continue
+		}
+		if c.NumExecutors > 0 && c.BusyExecutors() >= c.NumExecutors {
+			// All the executors on the node are busy.
+			otherrows = append(otherrows, len(rows)-1)
+		}
+
+		busy += c.BusyExecutors()
+		total += c.NumExecutors
+	}
+
+	// Set the rows.
+	table.Rows = rows
+
+	// Set the default colors and settings.
+	table.FgColor = termui.ColorWhite
+	table.BgColor = termui.ColorDefault
+	table.TextAlign = termui.AlignLeft
+	table.Border = true
+	table.Block.BorderLabel = fmt.Sprintf("Jenkins executors for %s (%d/%d busy)", d.name, busy, total)
+	table.Analysis()
+	table.SetSize()
+	// Set the color to red for the offline nodes
+	for _, br := range redrows {
+		table.FgColors[br] = termui.ColorRed
+	}
+	// Set the color to yellow for the nodes with no idle executors
+	for _, br := range otherrows {
+		table.FgColors[br] = termui.ColorYellow
+	}
+
+	return table
+}
+
+// printStage describes the stage of a Pipeline run that failed or is running,
+// ie. "failed at Integration Tests after 14m".
+func printStage(run jenkins.Run) string {
+	stage := run.Current()
+	if stage == nil {
+		return ""
+	}
+
+	switch stage.Status {
+	case jenkins.StatusInProgress:
+		return fmt.Sprintf("running %s for %s", stage.Name, printDuration(stage.Duration()))
+	case jenkins.StatusPaused:
+		return fmt.Sprintf("waiting for input at %s", stage.Name)
+	}
+	return fmt.Sprintf("%s at %s after %s", strings.ToLower(stage.Status), stage.Name, printDuration(run.Elapsed(*stage)))
+}
+
+// printStages lists the stages of a Pipeline run with how long each took,
+// up to the stage that failed or is running.
+func printStages(run jenkins.Run) string {
+	stages := []string{}
+	for _, stage := range run.Stages {
+		if stage.Status == jenkins.StatusNotExecuted {
+			continue
+		}
+		stages = append(stages, stage.Name+" "+printDuration(stage.Duration()))
+	}
+	return strings.Join(stages, " > ")
+}
+
+// printTests summarizes a test report, ie. "3 failed, 120 passed, 2 skipped".
+func printTests(report jenkins.TestReport) string {
+	if report.FailCount+report.PassCount+report.SkipCount <= 0 {
+		return ""
+	}
+
+	tests := []string{}
+	if report.FailCount > 0 {
+		tests = append(tests, fmt.Sprintf("%d failed", report.FailCount))
+	}
+	tests = append(tests, fmt.Sprintf("%d passed", report.PassCount))
+	if report.SkipCount > 0 {
+		tests = append(tests, fmt.Sprintf("%d skipped", report.SkipCount))
+	}
+	return strings.Join(tests, ", ")
+}
+
+// printHistory returns a strip of colored blocks for the builds, oldest
+// first, using the termui markup for colors.
+func printHistory(builds []jenkins.Build) string {
+	var b strings.Builder
+	for i := len(builds) - 1; i >= 0; i-- {
+		color := "yellow"
+		switch {
+		case builds[i].Passed():
+			color = "green"
+		case builds[i].Result == "FAILURE":
+			color = "red"
+		case builds[i].Result == "":
+			// The build is running.
+			color = "blue"
+		}
+		fmt.Fprintf(&b, "[■](fg-%s)", color)
+	}
+	return b.String()
+}
+
+// printFlakiness returns how flaky the job is as a percentage, or nothing if
+// there is no history for the job.
+func printFlakiness(job jenkins.Job) string {
+	if len(job.Builds) <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.0f%%", 100*job.Flakiness())
+}
+
+// renderDurations returns a sparkline of how long the finished builds took,
+// oldest first. It returns nil if there are no finished builds.
+func renderDurations(builds []jenkins.Build) *termui.Sparklines {
+	data := []int{}
+	var longest int64
+	for i := len(builds) - 1; i >= 0; i-- {
+		if builds[i].Result == "" {
+			continue
+		}
+		data = append(data, int(builds[i].Duration/1000))
+		if builds[i].Duration > longest {
+			longest = builds[i].Duration
+		}
+	}
+	if len(data) <= 0 {
+		return nil
+	}
+
+	sparkline := termui.NewSparkline()
+	sparkline.Data = data
+	sparkline.Height = 4
+	sparkline.LineColor = termui.ColorCyan
+	sparkline.Title = fmt.Sprintf("longest %s", printDuration(time.Duration(longest)*time.Millisecond))
 
-	return table, nil
+	sparklines := termui.NewSparklines(sparkline)
+	sparklines.BorderLabel = "Build durations"
+	sparklines.Height = sparkline.Height + 3
+	return sparklines
 }
@@ -0,0 +1,121 @@
+package jenkins
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ParameterDefinition describes a parameter of a parameterized job.
+type ParameterDefinition struct {
+	Name                  string         `json:"name,omitempty"`
+	Type                  string         `json:"type,omitempty"`
+	Description           string         `json:"description,omitempty"`
+	DefaultParameterValue ParameterValue `json:"defaultParameterValue,omitempty"`
+	// Choices holds the choices for a choice parameter.
+	Choices []string `json:"choices,omitempty"`
+}
+
+// ParameterValue describes the value of a parameter for a build.
+type ParameterValue struct {
+	Name  string      `json:"name,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// String returns the value formatted as it is passed to a build.
+// It returns an empty string if the value is not set, ie. for passwords.
+func (p ParameterValue) String() string {
+	if p.Value == nil {
+		return ""
+	}
+	return fmt.Sprint(p.Value)
+}
+
+// jobPropertiesResponse describes a response for the properties of a job.
+type jobPropertiesResponse struct {
+	Property []struct {
+		ParameterDefinitions []ParameterDefinition `json:"parameterDefinitions,omitempty"`
+	} `json:"property,omitempty"`
+}
+
+// buildActionsResponse describes a response for the actions of a build.
+type buildActionsResponse struct {
+	Actions []struct {
+		Parameters []ParameterValue `json:"parameters,omitempty"`
+	} `json:"actions,omitempty"`
+}
+
+// GetParameters gets the parameters of a job. It returns nil if the job is
+// not parameterized.
+func (c *Client) GetParameters(ctx context.Context, job Job) ([]ParameterDefinition, error) {
+	var r jobPropertiesResponse
+	tree := "property[parameterDefinitions[name,type,description,defaultParameterValue[value],choices]]"
+	uri := fmt.Sprintf("%s/api/json?tree=%s", strings.TrimSuffix(job.URL, "/"), url.QueryEscape(tree))
+	if err := c.getJSON(ctx, "parameters", uri, &r); err != nil {
+		return nil, err
+	}
+
+	var params []ParameterDefinition
+	for _, p := range r.Property {
+		params = append(params, p.ParameterDefinitions...)
+	}
+	return params, nil
+}
+
+// GetBuildParameters gets the parameters a build was run with.
+func (c *Client) GetBuildParameters(ctx context.Context, build Build) ([]ParameterValue, error) {
+	var r buildActionsResponse
+	tree := "actions[parameters[name,value]]"
+	uri := fmt.Sprintf("%s/api/json?tree=%s", strings.TrimSuffix(build.URL, "/"), url.QueryEscape(tree))
+	if err := c.getJSON(ctx, "build parameters", uri, &r); err != nil {
+		return nil, err
+	}
+
+	var params []ParameterValue
+	for _, a := range r.Actions {
+		params = append(params, a.Parameters...)
+	}
+	return params, nil
+}
+
+// Build triggers a build of the job. If params is not nil, the build is
+// triggered with the parameters, any that are left out get their defaults.
+func (c *Client) Build(ctx context.Context, job Job, params url.Values) error {
+	uri := strings.TrimSuffix(job.URL, "/") + "/build"
+	if params != nil {
+		uri = strings.TrimSuffix(job.URL, "/") + "/buildWithParameters"
+	}
+
+	_, err := c.post(ctx, "build", uri, params)
+	return err
+}
+
+// Rebuild triggers a build of the job with the parameters of its last build.
+// Parameters with hidden values, ie. passwords, get their default values.
+func (c *Client) Rebuild(ctx context.Context, job Job) error {
+	var params url.Values
+	if len(job.LastBuild.URL) > 0 {
+		values, err := c.GetBuildParameters(ctx, job.LastBuild)
+		if err != nil {
+			return err
+		}
+		if len(values) > 0 {
+			params = url.Values{}
+		}
+		for _, v := range values {
+			if v.Value != nil {
+				params.Set(v.Name, v.String())
+			}
+		}
+	}
+
+	return c.Build(ctx, job, params)
+}
+
+// Abort aborts a running build.
+func (c *Client) Abort(ctx context.Context, build Build) error {
+	uri := strings.TrimSuffix(build.URL, "/") + "/stop"
+	_, err := c.post(ctx, "abort", uri, nil)
+	return err
+}
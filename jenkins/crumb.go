@@ -0,0 +1,104 @@
+package jenkins
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Crumb describes a CSRF protection crumb from the Jenkins API.
+// It must be sent as a header with POST requests to instances with CSRF
+// protection turned on.
+type Crumb struct {
+	Crumb             string `json:"crumb,omitempty"`
+	CrumbRequestField string `json:"crumbRequestField,omitempty"`
+}
+
+// getCrumb returns the crumb to send with POST requests, getting one from the
+// crumb issuer if we do not have one yet. It returns nil if the instance does
+// not have CSRF protection turned on.
+func (c *Client) getCrumb(ctx context.Context) (*Crumb, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.crumbChecked {
+		return c.crumb, nil
+	}
+
+	var crumb Crumb
+	uri := c.Baseurl + "/crumbIssuer/api/json"
+	if err := c.getJSON(ctx, "crumb", uri, &crumb); err != nil {
+		if err, ok := err.(*statusError); ok && err.code == http.StatusNotFound {
+			// CSRF protection is turned off.
+			c.crumbChecked = true
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	c.crumb = &crumb
+	c.crumbChecked = true
+	return c.crumb, nil
+}
+
+// resetCrumb forgets the crumb so a new one is issued for the next POST
+// request, ie. after the session it was issued for expired.
+func (c *Client) resetCrumb() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.crumb = nil
+	c.crumbChecked = false
+}
+
+// post makes a POST request to the uri with the form, adding a crumb if the
+// instance has CSRF protection turned on. If the crumb is rejected, a new
+// one is issued and the request is made again. The body of the response is
+// closed, what describes the request for errors.
+func (c *Client) post(ctx context.Context, what, uri string, form url.Values) (*http.Response, error) {
+	for retried := false; ; retried = true {
+		crumb, err := c.getCrumb(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("getting jenkins crumb for %s failed: %v", what, err)
+		}
+
+		// set up the request
+		req, err := http.NewRequestWithContext(ctx, "POST", uri, strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if crumb != nil {
+			req.Header.Set(crumb.CrumbRequestField, crumb.Crumb)
+		}
+
+		// add the auth, if we have any
+		if len(c.Username) > 0 {
+			req.SetBasicAuth(c.Username, c.Token)
+		}
+
+		// do the request
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusForbidden && crumb != nil && !retried {
+			// The crumb might have expired with the session, try a new one.
+			c.resetCrumb()
+			continue
+		}
+
+		if resp.StatusCode >= 400 {
+			return nil, &statusError{method: "post", what: what, uri: uri, code: resp.StatusCode}
+		}
+
+		return resp, nil
+	}
+}
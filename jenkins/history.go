@@ -0,0 +1,43 @@
+package jenkins
+
+// Passed returns true if the build finished successfully.
+func (b Build) Passed() bool {
+	return b.Result == "SUCCESS"
+}
+
+// Broken returns true if the build finished with failures, ie. it failed or
+// is unstable.
+func (b Build) Broken() bool {
+	return b.Result == "FAILURE" || b.Result == "UNSTABLE"
+}
+
+// Flakiness returns how often the finished builds of the job flipped between
+// passing and broken, from 0 for a job that has been consistently green or
+// red to 1 for one that flips on every build. Builds that are running or
+// were aborted are ignored.
+func (j Job) Flakiness() float64 {
+	var (
+		last     *Build
+		flips    int
+		compared int
+	)
+	for i := range j.Builds {
+		b := &j.Builds[i]
+		if !b.Passed() && !b.Broken() {
+			continue
+		}
+
+		if last != nil {
+			compared++
+			if b.Passed() != last.Passed() {
+				flips++
+			}
+		}
+		last = b
+	}
+
+	if compared <= 0 {
+		return 0
+	}
+	return float64(flips) / float64(compared)
+}
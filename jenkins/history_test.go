@@ -0,0 +1,35 @@
+package jenkins
+
+import (
+	"testing"
+)
+
+func TestFlakiness(t *testing.T) {
+	testCases := []struct {
+		name    string
+		results []string
+		want    float64
+	}{
+		{name: "no builds"},
+		{name: "one build", results: []string{"FAILURE"}},
+		{name: "always passing", results: []string{"SUCCESS", "SUCCESS", "SUCCESS"}},
+		{name: "always broken", results: []string{"FAILURE", "UNSTABLE", "FAILURE"}},
+		{name: "flips every build", results: []string{"SUCCESS", "FAILURE", "SUCCESS", "UNSTABLE"}, want: 1},
+		{name: "flipped once", results: []string{"FAILURE", "SUCCESS", "SUCCESS"}, want: 0.5},
+		{name: "running and aborted ignored", results: []string{"SUCCESS", "", "ABORTED", "FAILURE", "NOT_BUILT", "FAILURE"}, want: 0.5},
+		{name: "only running", results: []string{"", ""}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			j := Job{}
+			for _, r := range tc.results {
+				j.Builds = append(j.Builds, Build{Result: r})
+			}
+
+			if got := j.Flakiness(); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
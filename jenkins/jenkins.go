@@ -2,10 +2,14 @@ package jenkins
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/http/cookiejar"
 	"net/url"
+	"path"
+	"sync"
 )
 
 // Client contains the information for connecting to a jenkins instance
@@ -13,6 +17,12 @@ type Client struct {
 	Baseurl  string `json:"base_url"`
 	Username string `json:"username"`
 	Token    string `json:"token"`
+
+	httpClient *http.Client
+
+	mu           sync.Mutex
+	crumb        *Crumb
+	crumbChecked bool
 }
 
 // JobsResponse describes a response for jobs.
@@ -22,9 +32,35 @@ type JobsResponse struct {
 
 // Job describes a job object from the Jenkins API.
 type Job struct {
+	Class       string `json:"_class,omitempty"`
 	Name        string `json:"name,omitempty"`
+	FullName    string `json:"fullName,omitempty"`
 	DisplayName string `json:"displayName,omitempty"`
+	URL         string `json:"url,omitempty"`
 	LastBuild   Build  `json:"lastBuild,omitempty"`
+	// Builds holds the most recent builds of the job, newest first, if
+	// they were asked for.
+	Builds []Build `json:"builds,omitempty"`
+	// Jobs holds the jobs in a folder, organization folder or multibranch
+	// project. It is nil for jobs that are not folders.
+	Jobs []Job `json:"jobs,omitempty"`
+
+	// Path is the path of display names from the root of the Jenkins
+	// instance to the job, ie. folder/job/branch.
+	Path string `json:"-"`
+}
+
+// folderClasses are the classes of jobs that hold other jobs.
+var folderClasses = map[string]bool{
+	"com.cloudbees.hudson.plugins.folder.Folder":                            true,
+	"jenkins.branch.OrganizationFolder":                                     true,
+	"org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject": true,
+}
+
+// IsFolder returns true if the job holds other jobs, ie. it is a folder,
+// organization folder or multibranch project.
+func (j Job) IsFolder() bool {
+	return j.Jobs != nil || folderClasses[j.Class]
 }
 
 // Build describes a build from the Jenkins API.
@@ -32,49 +68,135 @@ type Build struct {
 	Result    string `json:"result,omitempty"`
 	Number    int    `json:"number,omitempty"`
 	Timestamp int64  `json:"timestamp,omitempty"`
+	// Duration is how long the build took in milliseconds.
+	Duration int64  `json:"duration,omitempty"`
+	URL      string `json:"url,omitempty"`
 }
 
 // New sets the authentication for the Jenkins client
 // Password can be an API token as described in:
 // https://wiki.jenkins-ci.org/display/JENKINS/Authenticating+scripted+clients
-func New(uri, username, token string) *Client {
+// If httpClient is nil, http.DefaultClient is used. The client gets its own
+// cookie jar, since crumbs are only valid for the session they were issued
+// for.
+func New(uri, username, token string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	// This never returns an error.
+	jar, _ := cookiejar.New(nil)
+
 	return &Client{
 		Baseurl:  uri,
 		Username: username,
 		Token:    token,
+		httpClient: &http.Client{
+			Transport:     httpClient.Transport,
+			CheckRedirect: httpClient.CheckRedirect,
+			Timeout:       httpClient.Timeout,
+			Jar:           jar,
+		},
+	}
+}
+
+// GetJobs gets the jobs for a Jenkins instance. It descends into folders,
+// organization folders and multibranch projects up to depth levels deep
+// and returns only the jobs that are not folders, with their Path set.
+// If builds is more than zero, the Builds of each job are set to that many of
+// its most recent builds.
+func (c *Client) GetJobs(ctx context.Context, depth, builds int) ([]Job, error) {
+	if depth < 1 {
+		depth = 1
+	}
+
+	var r JobsResponse
+	uri := fmt.Sprintf("%s/api/json?tree=%s&depth=1", c.Baseurl, url.QueryEscape(jobsTree(depth, builds)))
+	if err := c.getJSON(ctx, "jobs", uri, &r); err != nil {
+		return nil, err
 	}
+
+	return flattenJobs(r.Jobs, ""), nil
+}
+
+// jobsTree returns the tree query for the jobs nested depth levels deep,
+// with the given number of their most recent builds.
+func jobsTree(depth, builds int) string {
+	fields := "_class,name,fullName,displayName,url,lastBuild[number,timestamp,result,url]"
+	if builds > 0 {
+		fields += fmt.Sprintf(",builds[number,result,duration,timestamp,url]{0,%d}", builds)
+	}
+	if depth > 1 {
+		fields += "," + jobsTree(depth-1, builds)
+	}
+	return "jobs[" + fields + "]"
+}
+
+// flattenJobs returns the jobs that are not folders from the tree of jobs,
+// setting their Path.
+func flattenJobs(jobs []Job, parent string) []Job {
+	flat := []Job{}
+	for _, job := range jobs {
+		name := job.DisplayName
+		if len(name) <= 0 {
+			name = job.Name
+		}
+		job.Path = path.Join(parent, name)
+
+		if job.IsFolder() {
+			flat = append(flat, flattenJobs(job.Jobs, job.Path)...)
+			continue
+		}
+
+		job.Jobs = nil
+		flat = append(flat, job)
+	}
+	return flat
 }
 
-// GetJobs gets the jobs for a Jenkins instance.
-func (c *Client) GetJobs() ([]Job, error) {
+// getJSON gets the uri and decodes the json response into v.
+// What describes the request for errors.
+func (c *Client) getJSON(ctx context.Context, what, uri string, v interface{}) error {
 	// set up the request
-	url := fmt.Sprintf("%s/api/json?tree=%s&depth=1", c.Baseurl, url.QueryEscape("jobs[name,displayName,lastBuild[number,timestamp,result]]"))
-	req, err := http.NewRequest("GET", url, bytes.NewBuffer([]byte{}))
+	req, err := http.NewRequestWithContext(ctx, "GET", uri, bytes.NewBuffer([]byte{}))
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	// add the auth
-	req.SetBasicAuth(c.Username, c.Token)
+	// add the auth, if we have any
+	if len(c.Username) > 0 {
+		req.SetBasicAuth(c.Username, c.Token)
+	}
 
 	// do the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
 	// check the status code
 	// it should be 200
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("jenkins get jobs request to %s responded with status %d", url, resp.StatusCode)
+		return &statusError{method: "get", what: what, uri: uri, code: resp.StatusCode}
 	}
 
-	var r JobsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
-		return nil, fmt.Errorf("decoding json response from jobs from %s failed: %v", url, err)
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding json response from %s from %s failed: %v", what, uri, err)
 	}
 
-	return r.Jobs, nil
+	return nil
+}
+
+// statusError is returned when a request responds with an unexpected status
+// code.
+type statusError struct {
+	method string
+	what   string
+	uri    string
+	code   int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("jenkins %s %s request to %s responded with status %d", e.method, e.what, e.uri, e.code)
 }
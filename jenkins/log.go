@@ -0,0 +1,160 @@
+package jenkins
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// logPollInterval is how often the log of a running build is polled for
+	// more output.
+	logPollInterval = 2 * time.Second
+	// logTailSize is how much of the end of the console output of a build
+	// is shown, so a huge log is not pulled down in one request.
+	logTailSize = 256 << 10
+)
+
+// StreamLog writes the tail of the console output of a build to w as it is
+// logged, using the progressive text endpoint. It returns once the build has
+// finished and all of its output was written, or the context is done.
+func (c *Client) StreamLog(ctx context.Context, build Build, w io.Writer) error {
+	base := strings.TrimSuffix(build.URL, "/") + "/logText/progressiveText"
+
+	// Start from the tail of the log.
+	size, err := c.getLogSize(ctx, base)
+	if err != nil {
+		return err
+	}
+	start := size - logTailSize
+	if start > 0 {
+		fmt.Fprintf(w, "... skipped the first %d KB of the log\n", start>>10)
+		// The tail starts in the middle of a line, so drop it.
+		w = &lineSkipper{w: w}
+	} else {
+		start = 0
+	}
+
+	for {
+		uri := fmt.Sprintf("%s?start=%d", base, start)
+		next, more, err := c.getLogText(ctx, uri, w)
+		if err != nil {
+			return err
+		}
+		start = next
+
+		if !more {
+			return nil
+		}
+
+		select {
+		case <-time.After(logPollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// getLogSize returns the size of the console output of a build so far, from
+// a HEAD request to the progressive text endpoint. If the size is not given,
+// it returns 0 so the whole log is shown.
+func (c *Client) getLogSize(ctx context.Context, uri string) (int64, error) {
+	// set up the request
+	req, err := http.NewRequestWithContext(ctx, "HEAD", uri, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	// add the auth, if we have any
+	if len(c.Username) > 0 {
+		req.SetBasicAuth(c.Username, c.Token)
+	}
+
+	// do the request
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	// check the status code
+	// it should be 200
+	if resp.StatusCode != http.StatusOK {
+		return 0, &statusError{method: "head", what: "log", uri: uri, code: resp.StatusCode}
+	}
+
+	size, err := strconv.ParseInt(resp.Header.Get("X-Text-Size"), 10, 64)
+	if err != nil {
+		return 0, nil
+	}
+	return size, nil
+}
+
+// lineSkipper is an io.Writer that drops what is written to it up to and
+// including the first newline.
+type lineSkipper struct {
+	w       io.Writer
+	skipped bool
+}
+
+// Write implements io.Writer.
+func (s *lineSkipper) Write(p []byte) (int, error) {
+	if s.skipped {
+		return s.w.Write(p)
+	}
+
+	i := bytes.IndexByte(p, '\n')
+	if i < 0 {
+		return len(p), nil
+	}
+	s.skipped = true
+	if _, err := s.w.Write(p[i+1:]); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// getLogText gets a chunk of console output from the progressive text
+// endpoint, writing it to w. It returns the offset to get the next chunk
+// from and whether the build is still logging.
+func (c *Client) getLogText(ctx context.Context, uri string, w io.Writer) (int64, bool, error) {
+	// set up the request
+	req, err := http.NewRequestWithContext(ctx, "GET", uri, nil)
+	if err != nil {
+		return 0, false, err
+	}
+
+	// add the auth, if we have any
+	if len(c.Username) > 0 {
+		req.SetBasicAuth(c.Username, c.Token)
+	}
+
+	// do the request
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, false, err
+	}
+	defer resp.Body.Close()
+
+	// check the status code
+	// it should be 200
+	if resp.StatusCode != http.StatusOK {
+		return 0, false, &statusError{method: "get", what: "log", uri: uri, code: resp.StatusCode}
+	}
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return 0, false, fmt.Errorf("reading log from %s failed: %v", uri, err)
+	}
+
+	next, err := strconv.ParseInt(resp.Header.Get("X-Text-Size"), 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("log from %s has an invalid X-Text-Size header: %v", uri, err)
+	}
+
+	return next, resp.Header.Get("X-More-Data") == "true", nil
+}
@@ -0,0 +1,88 @@
+package jenkins
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// QueueResponse describes a response for the build queue.
+type QueueResponse struct {
+	Items []QueueItem `json:"items,omitempty"`
+}
+
+// QueueItem describes an item in the build queue from the Jenkins API.
+type QueueItem struct {
+	ID           int    `json:"id,omitempty"`
+	Why          string `json:"why,omitempty"`
+	InQueueSince int64  `json:"inQueueSince,omitempty"`
+	Blocked      bool   `json:"blocked,omitempty"`
+	Buildable    bool   `json:"buildable,omitempty"`
+	Stuck        bool   `json:"stuck,omitempty"`
+	Task         Task   `json:"task,omitempty"`
+}
+
+// Task describes the job a queue item is for.
+type Task struct {
+	Name     string `json:"name,omitempty"`
+	FullName string `json:"fullName,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+// ComputersResponse describes a response for the computers.
+type ComputersResponse struct {
+	BusyExecutors  int        `json:"busyExecutors,omitempty"`
+	TotalExecutors int        `json:"totalExecutors,omitempty"`
+	Computers      []Computer `json:"computer,omitempty"`
+}
+
+// Computer describes a node, ie. the controller or an agent, from the
+// Jenkins API.
+type Computer struct {
+	DisplayName        string     `json:"displayName,omitempty"`
+	Offline            bool       `json:"offline,omitempty"`
+	TemporarilyOffline bool       `json:"temporarilyOffline,omitempty"`
+	OfflineCauseReason string     `json:"offlineCauseReason,omitempty"`
+	Idle               bool       `json:"idle,omitempty"`
+	NumExecutors       int        `json:"numExecutors,omitempty"`
+	Executors          []Executor `json:"executors,omitempty"`
+}
+
+// Executor describes an executor on a node.
+type Executor struct {
+	Idle bool `json:"idle,omitempty"`
+}
+
+// BusyExecutors returns the number of executors on the node that are
+// running a build.
+func (c Computer) BusyExecutors() int {
+	busy := 0
+	for _, e := range c.Executors {
+		if !e.Idle {
+			busy++
+		}
+	}
+	return busy
+}
+
+// GetQueue gets the items in the build queue for a Jenkins instance.
+func (c *Client) GetQueue(ctx context.Context) ([]QueueItem, error) {
+	var r QueueResponse
+	uri := fmt.Sprintf("%s/queue/api/json?tree=%s", c.Baseurl, url.QueryEscape("items[id,why,inQueueSince,blocked,buildable,stuck,task[name,fullName,url]]"))
+	if err := c.getJSON(ctx, "queue", uri, &r); err != nil {
+		return nil, err
+	}
+
+	return r.Items, nil
+}
+
+// GetComputers gets the nodes and their executors for a Jenkins instance.
+func (c *Client) GetComputers(ctx context.Context) ([]Computer, error) {
+	var r ComputersResponse
+	uri := fmt.Sprintf("%s/computer/api/json?tree=%s", c.Baseurl, url.QueryEscape("busyExecutors,totalExecutors,computer[displayName,offline,temporarilyOffline,offlineCauseReason,idle,numExecutors,executors[idle]]"))
+	if err := c.getJSON(ctx, "computers", uri, &r); err != nil {
+		return nil, err
+	}
+
+	return r.Computers, nil
+}
@@ -0,0 +1,81 @@
+package jenkins
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// PipelineClass is the class of Pipeline jobs.
+const PipelineClass = "org.jenkinsci.plugins.workflow.job.WorkflowJob"
+
+// IsPipeline returns true if the job is a Pipeline job, and so has stages.
+// Branches of multibranch projects are Pipeline jobs too.
+func (j Job) IsPipeline() bool {
+	return j.Class == PipelineClass
+}
+
+// Run describes a run of a Pipeline job from the Pipeline Stage View API.
+type Run struct {
+	ID              string  `json:"id,omitempty"`
+	Name            string  `json:"name,omitempty"`
+	Status          string  `json:"status,omitempty"`
+	StartTimeMillis int64   `json:"startTimeMillis,omitempty"`
+	DurationMillis  int64   `json:"durationMillis,omitempty"`
+	Stages          []Stage `json:"stages,omitempty"`
+}
+
+// Stage describes a stage of a Pipeline run.
+type Stage struct {
+	ID              string `json:"id,omitempty"`
+	Name            string `json:"name,omitempty"`
+	Status          string `json:"status,omitempty"`
+	StartTimeMillis int64  `json:"startTimeMillis,omitempty"`
+	DurationMillis  int64  `json:"durationMillis,omitempty"`
+}
+
+// The statuses for runs and stages from the Pipeline Stage View API.
+const (
+	StatusSuccess     = "SUCCESS"
+	StatusFailed      = "FAILED"
+	StatusUnstable    = "UNSTABLE"
+	StatusAborted     = "ABORTED"
+	StatusInProgress  = "IN_PROGRESS"
+	StatusPaused      = "PAUSED_PENDING_INPUT"
+	StatusNotExecuted = "NOT_EXECUTED"
+)
+
+// Duration returns how long the stage ran for.
+func (s Stage) Duration() time.Duration {
+	return time.Duration(s.DurationMillis) * time.Millisecond
+}
+
+// Current returns the stage that failed or is running, ie. the first stage
+// that did not succeed. It returns nil if every stage succeeded.
+func (r Run) Current() *Stage {
+	for i, s := range r.Stages {
+		if s.Status != StatusSuccess && s.Status != StatusNotExecuted {
+			return &r.Stages[i]
+		}
+	}
+	return nil
+}
+
+// Elapsed returns how long the run had been going when the stage finished,
+// or for running stages, how long the run has been going.
+func (r Run) Elapsed(s Stage) time.Duration {
+	end := s.StartTimeMillis + s.DurationMillis
+	return time.Duration(end-r.StartTimeMillis) * time.Millisecond
+}
+
+// GetStages gets the stages for a build of a Pipeline job.
+// It requires the Pipeline Stage View plugin.
+func (c *Client) GetStages(ctx context.Context, build Build) (Run, error) {
+	var r Run
+	uri := strings.TrimSuffix(build.URL, "/") + "/wfapi/describe"
+	if err := c.getJSON(ctx, "stages", uri, &r); err != nil {
+		return Run{}, err
+	}
+
+	return r, nil
+}
@@ -0,0 +1,72 @@
+package jenkins
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// TestReport describes the test report for a build from the Jenkins API.
+type TestReport struct {
+	PassCount int         `json:"passCount,omitempty"`
+	FailCount int         `json:"failCount,omitempty"`
+	SkipCount int         `json:"skipCount,omitempty"`
+	Suites    []TestSuite `json:"suites,omitempty"`
+}
+
+// TestSuite describes a suite of test cases in a test report.
+type TestSuite struct {
+	Name  string     `json:"name,omitempty"`
+	Cases []TestCase `json:"cases,omitempty"`
+}
+
+// TestCase describes a test case in a test report.
+type TestCase struct {
+	ClassName string `json:"className,omitempty"`
+	Name      string `json:"name,omitempty"`
+	// Status is one of PASSED, FIXED, SKIPPED, FAILED or REGRESSION.
+	Status string `json:"status,omitempty"`
+}
+
+// Failed returns true if the test case failed.
+func (c TestCase) Failed() bool {
+	return c.Status == "FAILED" || c.Status == "REGRESSION"
+}
+
+// FullName returns the class name and name of the test case.
+func (c TestCase) FullName() string {
+	if len(c.ClassName) <= 0 {
+		return c.Name
+	}
+	return c.ClassName + "." + c.Name
+}
+
+// FailedCases returns the first n failing test cases in the test report.
+func (r TestReport) FailedCases(n int) []TestCase {
+	failed := []TestCase{}
+	for _, s := range r.Suites {
+		for _, c := range s.Cases {
+			if len(failed) >= n {
+				return failed
+			}
+			if c.Failed() {
+				failed = append(failed, c)
+			}
+		}
+	}
+	return failed
+}
+
+// GetTestReport gets the test report for a build. It returns an error if
+// the build has no test report.
+func (c *Client) GetTestReport(ctx context.Context, build Build) (TestReport, error) {
+	var r TestReport
+	tree := "passCount,failCount,skipCount,suites[name,cases[className,name,status]]"
+	uri := fmt.Sprintf("%s/testReport/api/json?tree=%s", strings.TrimSuffix(build.URL, "/"), url.QueryEscape(tree))
+	if err := c.getJSON(ctx, "test report", uri, &r); err != nil {
+		return TestReport{}, err
+	}
+
+	return r, nil
+}
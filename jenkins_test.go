@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/jessfraz/tdash/jenkins"
+)
+
+// newJenkinsServer returns a test Jenkins instance with a single failed job
+// and a function to take it down or bring it back up.
+func newJenkinsServer(t *testing.T, name string) (*httptest.Server, func(bool)) {
+	var (
+		mu sync.Mutex
+		up = true
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !up {
+			http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `{"jobs":[{"_class":"hudson.model.FreeStyleProject","name":%[1]q,"fullName":%[1]q,"url":"%[2]s/job/%[1]s/","lastBuild":{"number":1,"result":"FAILURE","url":"%[2]s/job/%[1]s/1/"}}]}`, name, "http://"+r.Host)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func(u bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		up = u
+	}
+}
+
+func TestJenkinsSourceFetch(t *testing.T) {
+	a, setA := newJenkinsServer(t, "a")
+	b, setB := newJenkinsServer(t, "b")
+
+	s := &jenkinsSource{
+		settings: jenkinsSettings{Depth: 1, Concurrency: 1},
+		clients: []jenkinsClient{
+			{name: "a", client: jenkins.New(a.URL, "", "", nil)},
+			{name: "b", client: jenkins.New(b.URL, "", "", nil)},
+		},
+	}
+
+	testCases := []struct {
+		name    string
+		aUp     bool
+		bUp     bool
+		wantErr bool
+		// wantJobs is the number of jobs for each instance and wantFailed
+		// is whether it has an error.
+		wantJobs   []int
+		wantFailed []bool
+	}{
+		{name: "both down before they were fetched", wantErr: true},
+		{name: "only a up", aUp: true, wantJobs: []int{1, 0}, wantFailed: []bool{false, true}},
+		{name: "both up", aUp: true, bUp: true, wantJobs: []int{1, 1}, wantFailed: []bool{false, false}},
+		{name: "b down keeps its last jobs", aUp: true, wantJobs: []int{1, 1}, wantFailed: []bool{false, true}},
+		{name: "both down", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		setA(tc.aUp)
+		setB(tc.bUp)
+
+		v, err := s.Fetch(context.Background())
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+
+		data := v.([]jenkinsData)
+		for i, d := range data {
+			if len(d.jobs) != tc.wantJobs[i] {
+				t.Errorf("%s: %s has %d jobs, want %d", tc.name, d.name, len(d.jobs), tc.wantJobs[i])
+			}
+			if (d.err != nil) != tc.wantFailed[i] {
+				t.Errorf("%s: %s has error %v, want failed %t", tc.name, d.name, d.err, tc.wantFailed[i])
+			}
+		}
+
+		// The instance that failed is shown with its error.
+		if rows := s.Render(v); len(rows) != 1 || len(rows[0].Cols) != 2 {
+			t.Errorf("%s: got %d rows, want 1 row with a column per instance", tc.name, len(rows))
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"github.com/gizak/termui"
+)
+
+// stack is a termui.GridBufferer that stacks rows on top of each other so
+// all the rows for a data source fit in a single column of the layout.
+type stack struct {
+	termui.Grid
+}
+
+func newStack(rows ...*termui.Row) *stack {
+	return &stack{Grid: termui.Grid{Rows: rows}}
+}
+
+// GetHeight implements termui.GridBufferer.
+func (s *stack) GetHeight() int {
+	s.Align()
+
+	h := 0
+	for _, r := range s.Rows {
+		h += r.GetHeight()
+	}
+	return h
+}
+
+// SetWidth implements termui.GridBufferer.
+func (s *stack) SetWidth(w int) {
+	s.Width = w
+	s.Align()
+}
+
+// SetX implements termui.GridBufferer.
+func (s *stack) SetX(x int) {
+	s.X = x
+	s.Align()
+}
+
+// SetY implements termui.GridBufferer.
+func (s *stack) SetY(y int) {
+	s.Y = y
+	s.Align()
+}
+
+// layoutRows returns the rows for the termui body. If the configuration file
+// has a layout, each data source is placed in its column, otherwise the rows
+// for each data source are added one after another. The panes for the
+// selected item are added at the bottom.
+func layoutRows(layout [][]layoutCell) []*termui.Row {
+	rows := []*termui.Row{}
+
+	if len(layout) <= 0 {
+		for _, s := range sources {
+			rows = append(rows, s.render()...)
+		}
+		return append(rows, paneRows()...)
+	}
+
+	for _, cells := range layout {
+		cols := []*termui.Row{}
+		for _, cell := range cells {
+			s := lookupSource(cell.Source)
+			if s == nil {
+				continue
+			}
+
+			r := s.render()
+			if len(r) <= 0 {
+				continue
+			}
+
+			cols = append(cols, termui.NewCol(cell.columns(len(cells)), cell.Offset, newStack(r...)))
+		}
+
+		if len(cols) > 0 {
+			rows = append(rows, termui.NewRow(cols...))
+		}
+	}
+
+	return append(rows, paneRows()...)
+}
+
+// columnRows returns rows with the widgets split evenly across the columns.
+// Once there are more widgets than columns, they wrap onto as many rows as
+// needed so none of them is left without a column. It returns nil if there
+// are no widgets.
+func columnRows(widgets []termui.GridBufferer) []*termui.Row {
+	if len(widgets) <= 0 {
+		return nil
+	}
+
+	// Spread the widgets evenly over the rows.
+	n := (len(widgets) + 11) / 12
+	perRow := (len(widgets) + n - 1) / n
+
+	rows := []*termui.Row{}
+	for len(widgets) > 0 {
+		count := perRow
+		if count > len(widgets) {
+			count = len(widgets)
+		}
+
+		columns := []*termui.Row{}
+		for _, w := range widgets[:count] {
+			columns = append(columns, termui.NewCol(12/perRow, 0, w))
+		}
+		rows = append(rows, termui.NewRow(columns...))
+
+		widgets = widgets[count:]
+	}
+	return rows
+}
+
+// paneRows returns the rows shown below all the data sources: the detail
+// pane for the selected item, its log, the form or confirmation for an
+// action and the status of the last action.
+func paneRows() []*termui.Row {
+	rows := detailRows()
+	rows = append(rows, logRows()...)
+	rows = append(rows, promptRows()...)
+	return append(rows, toastRows()...)
+}
+
+// setBorderFg sets the border color for all the widgets in a row.
+func setBorderFg(r *termui.Row, color termui.Attribute) {
+	switch w := r.Widget.(type) {
+	case *termui.Table:
+		w.BorderFg = color
+	case *termui.Par:
+		w.BorderFg = color
+	case *termui.List:
+		w.BorderFg = color
+	case *termui.Sparklines:
+		w.BorderFg = color
+	case *stack:
+		for _, c := range w.Rows {
+			setBorderFg(c, color)
+		}
+	}
+
+	for _, c := range r.Cols {
+		setBorderFg(c, color)
+	}
+}
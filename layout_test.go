@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/gizak/termui"
+)
+
+// fakeSource is a data source that has no data yet.
+type fakeSource struct{}
+
+func (fakeSource) Name() string                                   { return "fake" }
+func (fakeSource) Configure(cfg *sourceConfig) (bool, error)      { return true, nil }
+func (fakeSource) Fetch(ctx context.Context) (interface{}, error) { return nil, nil }
+func (fakeSource) Render(data interface{}) []*termui.Row          { return nil }
+
+func TestLayoutRows(t *testing.T) {
+	type col struct {
+		span, offset int
+	}
+
+	testCases := []struct {
+		name   string
+		layout [][]layoutCell
+		want   [][]col
+	}{
+		{
+			name: "no layout",
+			want: [][]col{{{12, 0}}, {{12, 0}}, {{12, 0}}},
+		},
+		{
+			name:   "spans",
+			layout: [][]layoutCell{{{Source: "a", Span: 8}, {Source: "b", Span: 3, Offset: 1}}, {{Source: "c", Span: 12}}},
+			want:   [][]col{{{8, 0}, {3, 1}}, {{12, 0}}},
+		},
+		{
+			name:   "split evenly",
+			layout: [][]layoutCell{{{Source: "a"}, {Source: "b"}, {Source: "c"}}},
+			want:   [][]col{{{4, 0}, {4, 0}, {4, 0}}},
+		},
+		{
+			name:   "split evenly with a span",
+			layout: [][]layoutCell{{{Source: "a", Span: 8}, {Source: "b"}}},
+			want:   [][]col{{{8, 0}, {6, 0}}},
+		},
+		{
+			name:   "unknown source",
+			layout: [][]layoutCell{{{Source: "a", Span: 6}, {Source: "d", Span: 6}}, {{Source: "e"}}},
+			want:   [][]col{{{6, 0}}},
+		},
+	}
+
+	defer func(s []*dashSource) { sources = s }(sources)
+	sources = []*dashSource{
+		{Source: fakeSource{}, name: "a"},
+		{Source: fakeSource{}, name: "b"},
+		{Source: fakeSource{}, name: "c"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rows := layoutRows(tc.layout)
+
+			got := [][]col{}
+			for _, r := range rows {
+				cols := []col{}
+				if len(r.Cols) <= 0 {
+					// The rows for a data source without a layout.
+					cols = append(cols, col{r.Span, r.Offset})
+				} else {
+					for _, c := range r.Cols {
+						cols = append(cols, col{c.Span, c.Offset})
+					}
+				}
+				got = append(got, cols)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestColumnRows(t *testing.T) {
+	testCases := []struct {
+		widgets int
+		want    [][]int
+	}{
+		{widgets: 0, want: [][]int{}},
+		{widgets: 1, want: [][]int{{12}}},
+		{widgets: 3, want: [][]int{{4, 4, 4}}},
+		{widgets: 5, want: [][]int{{2, 2, 2, 2, 2}}},
+		{widgets: 12, want: [][]int{{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}}},
+		{widgets: 13, want: [][]int{{1, 1, 1, 1, 1, 1, 1}, {1, 1, 1, 1, 1, 1}}},
+		{widgets: 14, want: [][]int{{1, 1, 1, 1, 1, 1, 1}, {1, 1, 1, 1, 1, 1, 1}}},
+		{widgets: 26, want: [][]int{{1, 1, 1, 1, 1, 1, 1, 1, 1}, {1, 1, 1, 1, 1, 1, 1, 1, 1}, {1, 1, 1, 1, 1, 1, 1, 1}}},
+	}
+
+	for _, tc := range testCases {
+		widgets := []termui.GridBufferer{}
+		for i := 0; i < tc.widgets; i++ {
+			widgets = append(widgets, termui.NewPar("fake"))
+		}
+
+		got := [][]int{}
+		for _, r := range columnRows(widgets) {
+			spans := []int{}
+			for _, c := range r.Cols {
+				spans = append(spans, c.Span)
+			}
+			got = append(got, spans)
+		}
+
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%d widgets: got spans %v, want %v", tc.widgets, got, tc.want)
+		}
+	}
+}
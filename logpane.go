@@ -0,0 +1,423 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gizak/termui"
+)
+
+const (
+	// maxLogLines is the number of lines at the end of a log kept in the log
+	// pane.
+	maxLogLines = 10000
+	// logRenderInterval is the shortest time between renders for new log
+	// output, so a log written in many small chunks does not render the
+	// whole dashboard for each of them.
+	logRenderInterval = 200 * time.Millisecond
+)
+
+var (
+	// ansiRegexp matches ANSI escape sequences, ie. for colors.
+	ansiRegexp = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+	// errorRegexp matches lines that look like they describe an error.
+	errorRegexp = regexp.MustCompile(`(?i)(^|\W)(error|errors|failed|failure|fatal|panic|exception)(\W|$)`)
+)
+
+// logStreamer writes a build log to w as it is logged. It returns once the
+// build has finished or the context is done.
+type logStreamer func(ctx context.Context, w io.Writer) error
+
+// Logger is implemented by selectable data sources that can show the build
+// log of the selected item.
+type Logger interface {
+	// Log returns the title and the streamer for the log of the item with
+	// the key. The streamer is nil if the item has no log.
+	Log(data interface{}, key string) (string, logStreamer)
+}
+
+// logPane holds the build log being shown. While it is open, it gets all
+// the key presses.
+var logPane struct {
+	sync.Mutex
+	open    bool
+	title   string
+	status  string
+	lines   []string
+	partial string
+	// errLine is the index of the first line that looks like an error,
+	// or -1 if there is none.
+	errLine int
+	// offset is the index of the first line shown, or -1 to follow the
+	// end of the log.
+	offset    int
+	search    string
+	searching bool
+	cancel    context.CancelFunc
+	render    func()
+	// lastRender is when the log pane was last rendered for new output and
+	// renderPending is true if a render is already scheduled.
+	lastRender    time.Time
+	renderPending bool
+	// gen is incremented each time a log is opened, so writes for a log
+	// that was closed are dropped.
+	gen int
+}
+
+// logWriter is an io.Writer that adds what is written to it to the log
+// pane, stripping ANSI escape sequences.
+type logWriter struct {
+	gen int
+}
+
+// Write implements io.Writer.
+func (w logWriter) Write(p []byte) (int, error) {
+	logPane.Lock()
+
+	if !logPane.open || logPane.gen != w.gen {
+		logPane.Unlock()
+		return len(p), nil
+	}
+
+	// Only the new lines need checking for errors, unless the error line
+	// was dropped below.
+	scanFrom := len(logPane.lines)
+
+	text := logPane.partial + string(p)
+	lines := strings.Split(text, "\n")
+	// The last line is not done until we get its newline.
+	logPane.partial = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		logPane.lines = append(logPane.lines, cleanLogLine(line))
+	}
+
+	// Only keep the end of the log.
+	if drop := len(logPane.lines) - maxLogLines; drop > 0 {
+		logPane.lines = logPane.lines[drop:]
+		if logPane.errLine >= 0 && logPane.errLine < drop {
+			// Look for the next error in what we kept.
+			logPane.errLine = -1
+			scanFrom = 0
+		} else if logPane.errLine >= 0 {
+			logPane.errLine -= drop
+		}
+		scanFrom -= drop
+		if scanFrom < 0 {
+			scanFrom = 0
+		}
+		if logPane.offset >= 0 {
+			logPane.offset -= drop
+			if logPane.offset < 0 {
+				logPane.offset = 0
+			}
+		}
+	}
+
+	if logPane.errLine < 0 {
+		for i := scanFrom; i < len(logPane.lines); i++ {
+			if errorRegexp.MatchString(logPane.lines[i]) {
+				logPane.errLine = i
+				break
+			}
+		}
+	}
+
+	scheduleLogRender()
+	logPane.Unlock()
+
+	return len(p), nil
+}
+
+// scheduleLogRender renders the dashboard for new log output, waiting until
+// logRenderInterval has passed since the last render. The log pane must be
+// locked.
+func scheduleLogRender() {
+	if logPane.renderPending {
+		return
+	}
+	logPane.renderPending = true
+
+	render := logPane.render
+	time.AfterFunc(logRenderInterval-time.Since(logPane.lastRender), func() {
+		logPane.Lock()
+		logPane.renderPending = false
+		logPane.lastRender = time.Now()
+		logPane.Unlock()
+
+		render()
+	})
+}
+
+// cleanLogLine strips ANSI escape sequences from a line of a log and keeps
+// only what would be shown after the last carriage return, ie. for progress
+// bars.
+func cleanLogLine(line string) string {
+	line = strings.TrimRight(line, "\r")
+	if i := strings.LastIndex(line, "\r"); i >= 0 {
+		line = line[i+1:]
+	}
+	line = ansiRegexp.ReplaceAllString(line, "")
+	line = strings.Replace(line, "\t", "    ", -1)
+	// Make sure the line is not taken as termui markup.
+	return strings.Replace(line, "](", "] (", -1)
+}
+
+// openLog starts streaming the log of the selected item into the log pane.
+// It returns false if the selected item has no log.
+func openLog(ctx context.Context, render func()) bool {
+	selection.Lock()
+	s, selected := selection.source, selection.key
+	selection.Unlock()
+
+	if s == nil {
+		return false
+	}
+	l, ok := s.Source.(Logger)
+	if !ok {
+		return false
+	}
+
+	s.mu.Lock()
+	var (
+		title  string
+		stream logStreamer
+	)
+	if s.data != nil {
+		title, stream = l.Log(s.data, selected)
+	}
+	s.mu.Unlock()
+
+	if stream == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	logPane.Lock()
+	if logPane.cancel != nil {
+		logPane.cancel()
+	}
+	logPane.gen++
+	gen := logPane.gen
+	logPane.open = true
+	logPane.title = title
+	logPane.status = "streaming"
+	logPane.lines = nil
+	logPane.partial = ""
+	logPane.errLine = -1
+	logPane.offset = -1
+	logPane.search = ""
+	logPane.searching = false
+	logPane.cancel = cancel
+	logPane.render = render
+	logPane.Unlock()
+
+	go func() {
+		err := stream(ctx, logWriter{gen: gen})
+
+		logPane.Lock()
+		if ctx.Err() == context.Canceled || logPane.gen != gen {
+			// The log pane was closed.
+			logPane.Unlock()
+			return
+		}
+		if len(logPane.partial) > 0 {
+			logPane.lines = append(logPane.lines, cleanLogLine(logPane.partial))
+			logPane.partial = ""
+		}
+		logPane.status = "done"
+		if err != nil {
+			logPane.status = "error: " + err.Error()
+		}
+		logPane.Unlock()
+
+		render()
+	}()
+
+	return true
+}
+
+// handleLogKey handles a key press while the log pane is open. It returns
+// false if the log pane is not open, so the key press should be handled as
+// usual. Like handlePromptKey, it is only called from the goroutine handling
+// the keys, so the text typed into the search stays in order.
+func handleLogKey(key string) bool {
+	logPane.Lock()
+	defer logPane.Unlock()
+
+	if !logPane.open {
+		return false
+	}
+
+	if logPane.searching {
+		switch key {
+		case "<escape>":
+			logPane.searching = false
+			logPane.search = ""
+		case "<enter>":
+			logPane.searching = false
+			findLogLine(1)
+		case "<backspace>", "C-8":
+			if r := []rune(logPane.search); len(r) > 0 {
+				logPane.search = string(r[:len(r)-1])
+			}
+		case "<space>":
+			logPane.search += " "
+		default:
+			if len([]rune(key)) == 1 {
+				logPane.search += key
+			}
+		}
+		return true
+	}
+
+	page := logPageSize()
+	switch key {
+	case "q", "<escape>", "l":
+		logPane.open = false
+		logPane.cancel()
+		logPane.lines = nil
+	case "<up>", "k":
+		scrollLog(-1)
+	case "<down>", "j":
+		scrollLog(1)
+	case "<previous>", "C-b":
+		scrollLog(-page)
+	case "<next>", "C-f", "<space>":
+		scrollLog(page)
+	case "g", "<home>":
+		logPane.offset = 0
+	case "G", "<end>":
+		logPane.offset = -1
+	case "e":
+		if logPane.errLine >= 0 {
+			logPane.offset = logPane.errLine
+		}
+	case "/":
+		logPane.searching = true
+		logPane.search = ""
+	case "n":
+		findLogLine(1)
+	case "N":
+		findLogLine(-1)
+	}
+	return true
+}
+
+// logPageSize returns the number of log lines shown in the log pane.
+func logPageSize() int {
+	n := termui.TermHeight()/2 - 2
+	if n < 5 {
+		n = 5
+	}
+	return n
+}
+
+// logTop returns the index of the first line shown in the log pane.
+// The log pane must be locked.
+func logTop() int {
+	last := len(logPane.lines) - logPageSize()
+	if last < 0 {
+		last = 0
+	}
+	if logPane.offset < 0 || logPane.offset > last {
+		return last
+	}
+	return logPane.offset
+}
+
+// scrollLog scrolls the log pane by n lines, following the end of the log
+// again if it is scrolled to the bottom. The log pane must be locked.
+func scrollLog(n int) {
+	top := logTop() + n
+	if top < 0 {
+		top = 0
+	}
+	logPane.offset = top
+	if top >= len(logPane.lines)-logPageSize() {
+		logPane.offset = -1
+	}
+}
+
+// findLogLine scrolls to the next line matching the search, after the first
+// line shown if dir is 1 or before it if it is -1. The log pane must be
+// locked.
+func findLogLine(dir int) {
+	if len(logPane.search) <= 0 || len(logPane.lines) <= 0 {
+		return
+	}
+
+	search := strings.ToLower(logPane.search)
+	top := logTop()
+	for i := 1; i <= len(logPane.lines); i++ {
+		j := ((top+dir*i)%len(logPane.lines) + len(logPane.lines)) % len(logPane.lines)
+		if strings.Contains(strings.ToLower(logPane.lines[j]), search) {
+			logPane.offset = j
+			return
+		}
+	}
+}
+
+// logRows returns the rows for the log pane, if it is open.
+func logRows() []*termui.Row {
+	logPane.Lock()
+	defer logPane.Unlock()
+
+	if !logPane.open {
+		return nil
+	}
+
+	top := logTop()
+	end := top + logPageSize()
+	if end > len(logPane.lines) {
+		end = len(logPane.lines)
+	}
+
+	search := strings.ToLower(logPane.search)
+	items := []string{}
+	for i := top; i < end; i++ {
+		line := logPane.lines[i]
+		switch {
+		case i == logPane.errLine:
+			items = append(items, highlightLine(line, "fg-red,fg-bold"))
+		case len(search) > 0 && strings.Contains(strings.ToLower(line), search):
+			items = append(items, highlightLine(line, "fg-yellow"))
+		default:
+			items = append(items, "  "+line)
+		}
+	}
+
+	status := logPane.status
+	if logPane.offset < 0 && status == "streaming" {
+		status = "following"
+	}
+	if logPane.searching {
+		status = "search: /" + logPane.search + "_"
+	} else if len(logPane.search) > 0 {
+		status += ", n/N next/previous " + logPane.search
+	}
+
+	list := termui.NewList()
+	list.Items = items
+	list.ItemFgColor = termui.ColorWhite
+	list.BorderLabel = fmt.Sprintf("%s (%s) j/k scroll, e first error, / search, q close", logPane.title, status)
+	list.Height = logPageSize() + 2
+	if logPane.errLine >= 0 {
+		list.BorderFg = termui.ColorRed
+	}
+	return []*termui.Row{termui.NewRow(termui.NewCol(12, 0, list))}
+}
+
+// highlightLine returns the line marked up to be shown with the colors.
+// Lines with brackets cannot be marked up, so only their marker is colored.
+func highlightLine(line, colors string) string {
+	if strings.ContainsAny(line, "[]") || len(line) <= 0 {
+		return fmt.Sprintf("[>](%s) %s", colors, line)
+	}
+	return fmt.Sprintf("[>](%s) [%s](%s)", colors, line, colors)
+}
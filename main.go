@@ -4,13 +4,17 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/user"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/genuinetools/pkg/cli"
 	"github.com/gizak/termui"
+	"github.com/jessfraz/tdash/transport"
+	"github.com/jessfraz/tdash/travis"
 	"github.com/jessfraz/tdash/version"
 	"github.com/sirupsen/logrus"
 )
@@ -19,8 +23,14 @@ var (
 	googleAnalyticsKeyfile string
 	googleAnalyticsViewIDs stringSlice
 
-	travisToken  string
-	travisOwners stringSlice
+	githubToken     string
+	githubBaseURL   string
+	githubUploadURL string
+	githubOwners    stringSlice
+
+	travisEndpoint string
+	travisToken    string
+	travisOwners   stringSlice
 
 	jenkinsBaseURI  string
 	jenkinsUsername string
@@ -28,8 +38,22 @@ var (
 
 	showAllBuilds bool
 	interval      time.Duration
+	timeout       time.Duration
+
+	httpTimeout time.Duration
+	httpRetries int
+	// httpOptions holds the options for httpClient, so data sources that
+	// need their own TLS settings can create a client like it.
+	httpOptions transport.Options
+	// httpClient is the HTTP client shared by all the data sources.
+	httpClient *http.Client
+
+	dashDir    string
+	configFile string
 
-	dashDir string
+	// passedFlags holds the names of the global flags that were passed on
+	// the command line.
+	passedFlags = map[string]bool{}
 
 	debug bool
 )
@@ -65,12 +89,23 @@ func main() {
 
 	// Setup the global flags.
 	p.FlagSet = flag.NewFlagSet("global", flag.ExitOnError)
+	p.FlagSet.StringVar(&configFile, "config", filepath.Join(dashDir, "config.yaml"), "Path to config file describing the data sources and layout")
 	p.FlagSet.BoolVar(&showAllBuilds, "all", false, "Show all builds even successful ones, defaults to only showing failures")
 	p.FlagSet.DurationVar(&interval, "interval", 2*time.Minute, "update interval (ex. 5ms, 10s, 1m, 3h)")
+	p.FlagSet.DurationVar(&timeout, "timeout", time.Minute, "timeout for fetching the data for each source (ex. 5ms, 10s, 1m, 3h)")
+
+	p.FlagSet.DurationVar(&httpTimeout, "http-timeout", 30*time.Second, "timeout for each HTTP request (ex. 5ms, 10s, 1m, 3h)")
+	p.FlagSet.IntVar(&httpRetries, "http-retries", 3, "number of times to retry HTTP requests that failed with a 5xx or 429 status code")
 
 	p.FlagSet.StringVar(&googleAnalyticsKeyfile, "ga-keyfile", filepath.Join(dashDir, "ga.json"), "Path to Google Analytics keyfile")
 	p.FlagSet.Var(&googleAnalyticsViewIDs, "ga-viewid", "Google Analytics view IDs (can have more than one)")
 
+	p.FlagSet.StringVar(&githubToken, "github-token", os.Getenv("GITHUB_TOKEN"), "GitHub API token (or env var GITHUB_TOKEN)")
+	p.FlagSet.StringVar(&githubBaseURL, "github-url", "", "GitHub API base URL for GitHub Enterprise, ie. https://github.example.com/api/v3/")
+	p.FlagSet.StringVar(&githubUploadURL, "github-upload-url", "", "GitHub upload URL for GitHub Enterprise, defaults to the API base URL")
+	p.FlagSet.Var(&githubOwners, "github-owner", "GitHub owner name for the GitHub Actions, status, pull requests and repos data (can have more than one)")
+
+	p.FlagSet.StringVar(&travisEndpoint, "travis-endpoint", travis.DefaultEndpoint, "Travis CI API endpoint, ie. for travis-ci.com or a Travis CI Enterprise install")
 	p.FlagSet.StringVar(&travisToken, "travis-token", os.Getenv("TRAVISCI_API_TOKEN"), "Travis CI API token (or env var TRAVISCI_API_TOKEN)")
 	p.FlagSet.Var(&travisOwners, "travis-owner", "Travis owner name for builds (can have more than one)")
 
@@ -78,7 +113,7 @@ func main() {
 	p.FlagSet.StringVar(&jenkinsUsername, "jenkins-username", os.Getenv("JENKINS_USERNAME"), "Jenkins username for authentication (or env var JENKINS_USERNAME)")
 	p.FlagSet.StringVar(&jenkinsPassword, "jenkins-password", os.Getenv("JENKINS_PASSWORD"), "Jenkins password for authentication (or env var JENKINS_PASSWORD)")
 
-	p.FlagSet.BoolVar(&debug, "d", false, "enable debug logging")
+	p.FlagSet.BoolVar(&debug, "d", false, "enable debug logging, including all HTTP requests and responses, to ~/.tdash/logs/tdash.log")
 
 	// Set the before function.
 	p.Before = func(ctx context.Context) error {
@@ -87,43 +122,159 @@ func main() {
 			logrus.SetLevel(logrus.DebugLevel)
 		}
 
+		// Get the flags that were passed so they can override the config file.
+		p.FlagSet.Visit(func(f *flag.Flag) {
+			passedFlags[f.Name] = true
+		})
+
 		return nil
 	}
 
 	// Set the main program action.
 	p.Action = func(ctx context.Context, args []string) error {
-		ticker := time.NewTicker(interval)
+		// Read the config file.
+		c, err := readConfig(configFile, flagPassed("config"))
+		if err != nil {
+			return err
+		}
+		if c.Interval > 0 && !flagPassed("interval") {
+			interval = c.Interval
+		}
+		if c.Timeout > 0 && !flagPassed("timeout") {
+			timeout = c.Timeout
+		}
+		if c.All != nil && !flagPassed("all") {
+			showAllBuilds = *c.All
+		}
+
+		if c.HTTP.Timeout > 0 && !flagPassed("http-timeout") {
+			httpTimeout = c.HTTP.Timeout
+		}
+		if c.HTTP.Retries != nil && !flagPassed("http-retries") {
+			httpRetries = *c.HTTP.Retries
+		}
+
+		// Open the log file.
+		logFile, err := openLogFile()
+		if err != nil {
+			return err
+		}
+		defer logFile.Close()
+
+		// Create the HTTP client shared by the data sources.
+		httpOptions = transport.Options{
+			Timeout:    httpTimeout,
+			MaxRetries: httpRetries,
+			MaxWait:    timeout,
+		}
+		if debug {
+			// Log all the requests and responses.
+			httpOptions.Debug = logFile
+		}
+		httpClient = transport.NewClient(httpOptions)
+
+		// Configure the data sources.
+		if err := configureSources(c); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		ticker := time.NewTicker(minInterval())
 
 		// Initialize termui.
+		logrus.Infof("logging to %s", logFile.Name())
 		if err := termui.Init(); err != nil {
-			logrus.Fatalf("initializing termui failed: %v", err)
+			return fmt.Errorf("initializing termui failed: %v", err)
 		}
 		defer termui.Close()
 
-		go doWidgets()
+		// Send the logs to the log file while termui is running so they do
+		// not corrupt the screen. Until now they still go to stderr, so an
+		// error starting up is not hidden in the log file.
+		logrus.SetOutput(logFile)
+		logrus.SetFormatter(&logrus.TextFormatter{DisableColors: true, FullTimestamp: true})
+		defer func() {
+			logrus.SetOutput(os.Stderr)
+			logrus.SetFormatter(&logrus.TextFormatter{})
+		}()
 
-		// Handle key q pressing
-		termui.Handle("/sys/kbd/q", func(termui.Event) {
-			// press q to quit
-			ticker.Stop()
-			termui.StopLoop()
-		})
+		go refreshSources(ctx, c.Layout)
 
-		termui.Handle("/sys/kbd/C-c", func(termui.Event) {
-			// handle Ctrl + c combination
-			ticker.Stop()
-			termui.StopLoop()
-		})
+		render := func() {
+			renderWidgets(c.Layout)
+		}
 
-		// Handle resize
+		// Handle resize by rendering the data we already have.
 		termui.Handle("/sys/wnd/resize", func(e termui.Event) {
-			doWidgets()
+			render()
 		})
 
-		// Update on an interval
+		// Handle all the key presses here, so an open form, confirmation
+		// or log gets them first. Ctrl + c always quits though.
+		handleKey := func(key string) {
+			if key != "C-c" && (handlePromptKey(ctx, key, render) || handleLogKey(key)) {
+				render()
+				return
+			}
+
+			switch key {
+			case "q", "C-c":
+				// press q or Ctrl + c to quit
+				cancel()
+				ticker.Stop()
+				termui.StopLoop()
+				return
+			case "C-l":
+				// Ctrl + l redraws the screen
+			case "<down>", "j":
+				moveSelection(c.Layout, 1)
+			case "<up>", "k":
+				moveSelection(c.Layout, -1)
+			case "<enter>":
+				toggleDetail()
+			case "<escape>":
+				clearSelection()
+			case "l":
+				// Open the log of the selected build.
+				if !openLog(ctx, render) {
+					return
+				}
+			default:
+				// Run the action for the key on the selected item.
+				if !startAction(ctx, key, render) {
+					return
+				}
+			}
+			render()
+		}
+
+		// termui runs each handler in its own goroutine, so the keys are
+		// sent to a single goroutine that handles them one at a time in
+		// the order they were pressed.
+		keys := make(chan string, 64)
+		termui.Handle("/sys/kbd", func(e termui.Event) {
+			select {
+			case keys <- e.Data.(termui.EvtKbd).KeyStr:
+			case <-ctx.Done():
+			}
+		})
+		go func() {
+			for {
+				select {
+				case key := <-keys:
+					handleKey(key)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		// Only fetch new data on an interval
 		go func() {
 			for range ticker.C {
-				doWidgets()
+				refreshSources(ctx, c.Layout)
 			}
 		}()
 
@@ -136,6 +287,34 @@ func main() {
 	p.Run()
 }
 
+// maxLogFileSize is the size past which the log file is started over, so
+// it does not grow forever.
+const maxLogFileSize = 10 << 20
+
+// openLogFile opens the log file in the logs directory in dashDir for
+// appending, creating it if it does not exist. If it is over maxLogFileSize,
+// it is first moved to tdash.log.1, replacing the one moved there before.
+func openLogFile() (*os.File, error) {
+	dir := filepath.Join(dashDir, "logs")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("creating logs directory %q failed: %v", dir, err)
+	}
+
+	file := filepath.Join(dir, "tdash.log")
+	if fi, err := os.Stat(file); err == nil && fi.Size() > maxLogFileSize {
+		if err := os.Rename(file, file+".1"); err != nil {
+			return nil, fmt.Errorf("rotating log file %q failed: %v", file, err)
+		}
+	}
+
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("opening log file %q failed: %v", file, err)
+	}
+
+	return f, nil
+}
+
 func getHome() (string, error) {
 	home := os.Getenv(homeKey)
 	if home != "" {
@@ -149,60 +328,43 @@ func getHome() (string, error) {
 	return u.HomeDir, nil
 }
 
-func doWidgets() {
-	body := termui.NewGrid()
-	body.X = 0
-	body.Y = 0
-	body.BgColor = termui.ThemeAttr("bg")
-	body.Width = termui.TermWidth()
+// refreshSources fetches the data for each of the sources that is due for an
+// update in parallel, rendering the termui body as each one finishes.
+func refreshSources(ctx context.Context, layout [][]layoutCell) {
+	slack := minInterval() / 2
+	for _, s := range sources {
+		if !s.due(slack) {
+			continue
+		}
 
-	ga, err := doGoogleAnalytics()
-	if err != nil {
-		termui.StopLoop()
-		termui.Close()
-		logrus.Fatal(err)
+		go func(s *dashSource) {
+			s.fetch(ctx)
+			renderWidgets(layout)
+		}(s)
 	}
 
-	// Add Google Analytics data to the termui body.
-	for _, data := range ga {
-		data.table.Block.BorderLabel = "Google Analytics data for " + data.name
+	// Render what we have while we wait on the data sources.
+	renderWidgets(layout)
+}
 
-		activeUsers := termui.NewPar(data.activeUsers)
-		activeUsers.TextFgColor = termui.ColorWhite
-		activeUsers.BorderFg = termui.ColorWhite
-		activeUsers.BorderLabel = "Active users for " + data.name
-		activeUsers.Height = 3
+// renderMu ensures only one render of the termui body happens at a time.
+var renderMu sync.Mutex
 
-		if data.table != nil {
-			body.AddRows(
-				termui.NewRow(termui.NewCol(9, 0, data.table), termui.NewCol(3, 0, activeUsers)),
-			)
-		}
-	}
+// renderWidgets renders the termui body from the last data fetched for each
+// of the sources. It does not make any requests so it is cheap to call on
+// resize or keypress.
+func renderWidgets(layout [][]layoutCell) {
+	renderMu.Lock()
+	defer renderMu.Unlock()
 
-	travis, err := doTravisCI()
-	if err != nil {
-		termui.StopLoop()
-		termui.Close()
-		logrus.Fatal(err)
-	}
-	if travis != nil {
-		columns := []*termui.Row{}
-		for _, t := range travis {
-			columns = append(columns, termui.NewCol(int(12/len(travis)), 0, t))
-		}
-		body.AddRows(termui.NewRow(columns...))
-	}
+	body := termui.NewGrid()
+	body.X = 0
+	body.Y = 0
+	body.BgColor = termui.ThemeAttr("bg")
+	body.Width = termui.TermWidth()
 
-	janky, err := doJenkinsCI()
-	if err != nil {
-		termui.StopLoop()
-		termui.Close()
-		logrus.Fatal(err)
-	}
-	if janky != nil {
-		body.AddRows(termui.NewCol(3, 0, janky))
-	}
+	// Add the data from each of the sources to the termui body.
+	body.AddRows(layoutRows(layout)...)
 
 	// Calculate the layout.
 	body.Align()
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenLogFileRotates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tdash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	old := dashDir
+	dashDir = dir
+	t.Cleanup(func() { dashDir = old })
+
+	file := filepath.Join(dir, "logs", "tdash.log")
+	open := func() {
+		f, err := openLogFile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+
+	// A small log file is appended to.
+	open()
+	if err := ioutil.WriteFile(file, []byte("small\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	open()
+	if fi, err := os.Stat(file); err != nil || fi.Size() != 6 {
+		t.Fatalf("the small log file was not kept: %v", err)
+	}
+
+	// A log file over the limit is moved out of the way.
+	if err := os.Truncate(file, maxLogFileSize+1); err != nil {
+		t.Fatal(err)
+	}
+	open()
+	if fi, err := os.Stat(file); err != nil || fi.Size() != 0 {
+		t.Fatalf("the log file over the limit was not started over: %v", err)
+	}
+	if fi, err := os.Stat(file + ".1"); err != nil || fi.Size() != maxLogFileSize+1 {
+		t.Fatalf("the log file over the limit was not moved to %s.1: %v", file, err)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/gizak/termui"
+)
+
+// Selectable is implemented by data sources with items that can be selected
+// to show more detail about them.
+type Selectable interface {
+	// Items returns the keys of the items in the data that can be selected,
+	// in the order they are rendered.
+	Items(data interface{}) []string
+	// Detail returns the rows for the detail pane for the item with the key.
+	Detail(data interface{}, key string) []*termui.Row
+}
+
+// selection holds the item selected on the dashboard and whether its detail
+// pane is open.
+var selection struct {
+	sync.Mutex
+	source *dashSource
+	key    string
+	open   bool
+}
+
+// selectionItem is an item that can be selected on the dashboard.
+type selectionItem struct {
+	source *dashSource
+	key    string
+}
+
+// selectedKey returns the key of the selected item if it belongs to the data
+// source, so the data source can highlight it when rendering.
+func selectedKey(s Source) string {
+	selection.Lock()
+	defer selection.Unlock()
+
+	if selection.source == nil || selection.source.Source != s {
+		return ""
+	}
+	return selection.key
+}
+
+// orderedSources returns the configured data sources in the order they are
+// shown on the dashboard.
+func orderedSources(layout [][]layoutCell) []*dashSource {
+	if len(layout) <= 0 {
+		return sources
+	}
+
+	ordered := []*dashSource{}
+	for _, cells := range layout {
+		for _, cell := range cells {
+			if s := lookupSource(cell.Source); s != nil {
+				ordered = append(ordered, s)
+			}
+		}
+	}
+	return ordered
+}
+
+// selectionItems returns all the items that can be selected on the dashboard
+// in the order they are shown.
+func selectionItems(layout [][]layoutCell) []selectionItem {
+	items := []selectionItem{}
+	for _, s := range orderedSources(layout) {
+		sel, ok := s.Source.(Selectable)
+		if !ok {
+			continue
+		}
+
+		s.mu.Lock()
+		var keys []string
+		if s.data != nil {
+			keys = sel.Items(s.data)
+		}
+		s.mu.Unlock()
+
+		for _, key := range keys {
+			items = append(items, selectionItem{source: s, key: key})
+		}
+	}
+	return items
+}
+
+// moveSelection moves the selection by delta items, wrapping around at the
+// start and end of the dashboard. If nothing is selected, the first or last
+// item is selected.
+func moveSelection(layout [][]layoutCell, delta int) {
+	items := selectionItems(layout)
+	if len(items) <= 0 {
+		return
+	}
+
+	selection.Lock()
+	defer selection.Unlock()
+
+	i := -1
+	for j, item := range items {
+		if item.source == selection.source && item.key == selection.key {
+			i = j
+			break
+		}
+	}
+
+	if i < 0 {
+		if delta < 0 {
+			i = len(items) - 1
+		} else {
+			i = 0
+		}
+	} else {
+		i = ((i+delta)%len(items) + len(items)) % len(items)
+	}
+
+	selection.source = items[i].source
+	selection.key = items[i].key
+}
+
+// toggleDetail opens or closes the detail pane for the selected item.
+func toggleDetail() {
+	selection.Lock()
+	defer selection.Unlock()
+
+	if selection.source == nil {
+		return
+	}
+	selection.open = !selection.open
+}
+
+// clearSelection closes the detail pane, or if it is not open, unselects
+// the selected item.
+func clearSelection() {
+	selection.Lock()
+	defer selection.Unlock()
+
+	if selection.open {
+		selection.open = false
+		return
+	}
+	selection.source = nil
+	selection.key = ""
+}
+
+// detailRows returns the rows for the detail pane of the selected item, or
+// nil if the detail pane is not open.
+func detailRows() []*termui.Row {
+	selection.Lock()
+	s, key, open := selection.source, selection.key, selection.open
+	selection.Unlock()
+
+	if !open || s == nil {
+		return nil
+	}
+
+	sel, ok := s.Source.(Selectable)
+	if !ok {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data == nil {
+		return nil
+	}
+	return sel.Detail(s.data, key)
+}
+
+// highlightRow highlights the row of the table for the selected item.
+func highlightRow(table *termui.Table, row int) {
+	table.FgColors[row] |= termui.AttrReverse
+}
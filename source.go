@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gizak/termui"
+	"github.com/sirupsen/logrus"
+)
+
+// Source describes a data source for the dashboard.
+type Source interface {
+	// Name returns the name of the data source.
+	Name() string
+	// Configure validates the settings for the data source and sets up any
+	// clients it needs. It returns false if the data source is not
+	// configured and should be skipped.
+	Configure(cfg *sourceConfig) (bool, error)
+	// Fetch gets the latest data for the data source. It should return
+	// early once the context is done.
+	Fetch(ctx context.Context) (interface{}, error)
+	// Render returns the termui rows for data returned by Fetch.
+	Render(data interface{}) []*termui.Row
+}
+
+// Intervaler is implemented by data sources that change slowly or are costly
+// to fetch, so they are refreshed less often than the global interval unless
+// they have an interval of their own.
+type Intervaler interface {
+	// DefaultInterval returns the shortest interval the data source is
+	// refreshed at by default.
+	DefaultInterval() time.Duration
+}
+
+// sourceType holds a registered kind of data source.
+type sourceType struct {
+	name      string
+	newSource func() Source
+}
+
+// dashSource holds a data source configured on the dashboard along with the
+// last data fetched for it. The termui body is always rendered from this
+// data, only the interval ticker fetches new data.
+type dashSource struct {
+	Source
+
+	name     string
+	interval time.Duration
+	timeout  time.Duration
+
+	mu          sync.Mutex
+	data        interface{}
+	err         error
+	failures    int
+	fetching    bool
+	lastFetch   time.Time
+	lastSuccess time.Time
+	nextFetch   time.Time
+}
+
+const (
+	// minBackoff is how long to wait before fetching a data source again
+	// after it first fails. It doubles for each failure after that.
+	minBackoff = 15 * time.Second
+	// maxBackoff is the most we will wait before fetching a data source
+	// again after it fails.
+	maxBackoff = 30 * time.Minute
+)
+
+// fetchResult holds the result of a call to Fetch.
+type fetchResult struct {
+	data interface{}
+	err  error
+}
+
+var (
+	// sourceTypes holds the registered kinds of data sources in the order
+	// they were registered.
+	sourceTypes []sourceType
+
+	// sources holds the configured data sources.
+	sources []*dashSource
+)
+
+// registerSource registers a kind of data source with the dashboard.
+// It should be called from an init function in the file implementing the
+// data source.
+func registerSource(name string, newSource func() Source) {
+	if _, ok := lookupSourceType(name); ok {
+		panic(fmt.Sprintf("data source %q is already registered", name))
+	}
+
+	sourceTypes = append(sourceTypes, sourceType{name: name, newSource: newSource})
+}
+
+// lookupSourceType returns the registered kind of data source with the
+// given name.
+func lookupSourceType(name string) (sourceType, bool) {
+	for _, t := range sourceTypes {
+		if t.name == name {
+			return t, true
+		}
+	}
+	return sourceType{}, false
+}
+
+// configureSources creates and configures the data sources from the
+// configuration file, skipping the ones that are not configured.
+// If the configuration file has no data sources, one of each of the
+// registered kinds is configured from the global flags.
+func configureSources(c *config) error {
+	cfgs := c.Sources
+	if len(cfgs) <= 0 {
+		for _, t := range sourceTypes {
+			cfgs = append(cfgs, &sourceConfig{Name: t.name, Type: t.name})
+		}
+	}
+
+	// Get the data sources used in the layout, if we have one.
+	inLayout := map[string]bool{}
+	for _, row := range c.Layout {
+		for _, cell := range row {
+			inLayout[cell.Source] = true
+		}
+	}
+
+	sources = []*dashSource{}
+	for _, cfg := range cfgs {
+		if len(c.Layout) > 0 && !inLayout[cfg.Name] {
+			logrus.Debugf("skipping data source %q since it is not in the layout", cfg.Name)
+			continue
+		}
+
+		t, ok := lookupSourceType(cfg.Type)
+		if !ok {
+			return fmt.Errorf("data source %q has unknown type %q", cfg.Name, cfg.Type)
+		}
+
+		s := t.newSource()
+		ok, err := s.Configure(cfg)
+		if err != nil {
+			return fmt.Errorf("configuring %s data source %q failed: %v", s.Name(), cfg.Name, err)
+		}
+		if !ok {
+			logrus.Infof("skipping %s data for %q", s.Name(), cfg.Name)
+			continue
+		}
+
+		// Use the global interval and timeout if the data source does
+		// not have them.
+		d := &dashSource{
+			Source:   s,
+			name:     cfg.Name,
+			interval: cfg.Interval,
+			timeout:  cfg.Timeout,
+		}
+		if d.interval <= 0 {
+			d.interval = interval
+			if i, ok := s.(Intervaler); ok && d.interval < i.DefaultInterval() {
+				d.interval = i.DefaultInterval()
+			}
+		}
+		if d.timeout <= 0 {
+			d.timeout = timeout
+		}
+
+		sources = append(sources, d)
+	}
+
+	return nil
+}
+
+// lookupSource returns the configured data source with the given name.
+func lookupSource(name string) *dashSource {
+	for _, s := range sources {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// minInterval returns the shortest update interval of the configured data
+// sources.
+func minInterval() time.Duration {
+	min := interval
+	for _, s := range sources {
+		if s.interval < min {
+			min = s.interval
+		}
+	}
+	return min
+}
+
+// due returns true if the data source should be fetched. The slack allows
+// for ticks that land a little before the next fetch is due.
+// It marks the data source as fetching so it is not fetched twice at once.
+func (s *dashSource) due(slack time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fetching {
+		return false
+	}
+	if time.Now().Add(slack).Before(s.nextFetch) {
+		return false
+	}
+
+	s.fetching = true
+	s.lastFetch = time.Now()
+	return true
+}
+
+// fetch gets the latest data for the data source within its timeout.
+// If the fetch fails or times out, the data from the last successful fetch
+// is kept and the next fetch is backed off.
+func (s *dashSource) fetch(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	// Not every client takes a context, so wait on the result
+	// and the context so the timeout is always respected.
+	ch := make(chan fetchResult, 1)
+	go func() {
+		data, err := s.Fetch(ctx)
+		ch <- fetchResult{data: data, err: err}
+	}()
+
+	var r fetchResult
+	select {
+	case r = <-ch:
+	case <-ctx.Done():
+		r.err = ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fetching = false
+	if r.err != nil {
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			r.err = fmt.Errorf("timed out after %s", s.timeout)
+		case context.Canceled:
+			// The dashboard is shutting down.
+			return
+		}
+
+		s.err = r.err
+		s.failures++
+		s.nextFetch = time.Now().Add(backoff(s.failures))
+		logrus.Warnf("fetching %s data for %q failed, retrying after %s: %v", s.Name(), s.name, s.nextFetch.Format(time.Kitchen), r.err)
+		return
+	}
+
+	s.err = nil
+	s.failures = 0
+	s.nextFetch = s.lastFetch.Add(s.interval)
+	s.lastSuccess = time.Now()
+	s.data = r.data
+}
+
+// backoff returns how long to wait before fetching a data source again
+// after it failed the given number of times in a row.
+func backoff(failures int) time.Duration {
+	d := minBackoff
+	for i := 1; i < failures && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
+
+// render returns the termui rows for the data source. If there is no data
+// yet, a placeholder is returned showing the data source is loading.
+// If the last fetch failed, the borders are turned red and the error is
+// shown below the data from the last successful fetch.
+func (s *dashSource) render() []*termui.Row {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows := []*termui.Row{}
+	if s.data != nil {
+		rows = s.Render(s.data)
+	}
+
+	if s.err == nil {
+		if s.data == nil {
+			rows = append(rows, s.statusRow("loading...", termui.ColorYellow))
+		}
+		return rows
+	}
+
+	// Show the error and when we last had good data.
+	text := "error: " + s.err.Error()
+	if !s.lastSuccess.IsZero() {
+		text += " (last success " + s.lastSuccess.Local().Format("Mon, Jan 02 15:04 MST") + ")"
+	}
+	for _, r := range rows {
+		setBorderFg(r, termui.ColorRed)
+	}
+	return append(rows, s.statusRow(text, termui.ColorRed))
+}
+
+// statusRow returns a row with a paragraph describing the status of the
+// data source.
+func (s *dashSource) statusRow(text string, color termui.Attribute) *termui.Row {
+	p := termui.NewPar(text)
+	p.TextFgColor = color
+	p.BorderFg = color
+	p.BorderLabel = s.Name() + " data for " + s.name
+	p.Height = 3
+
+	return termui.NewRow(termui.NewCol(12, 0, p))
+}
+
+// printCommit returns the short SHA and the start of the subject of a commit.
+func printCommit(sha, message string) string {
+	if len(sha) > 7 {
+		sha = sha[:7]
+	}
+	if r := []rune(message); len(r) > 40 {
+		message = string(r[:39]) + "…"
+	}
+	return strings.TrimSpace(sha + " " + message)
+}
+
+// printDuration returns a short human readable version of a duration,
+// ie. 14m or 2h3m.
+func printDuration(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(time.Second).String()
+	}
+	if d < 48*time.Hour {
+		return strings.TrimSuffix(d.Round(time.Minute).String(), "0s")
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
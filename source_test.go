@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	testCases := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 0, want: 15 * time.Second},
+		{failures: 1, want: 15 * time.Second},
+		{failures: 2, want: 30 * time.Second},
+		{failures: 3, want: time.Minute},
+		{failures: 4, want: 2 * time.Minute},
+		{failures: 7, want: 16 * time.Minute},
+		{failures: 8, want: 30 * time.Minute},
+		{failures: 100, want: 30 * time.Minute},
+	}
+
+	for _, tc := range testCases {
+		if got := backoff(tc.failures); got != tc.want {
+			t.Errorf("backoff(%d): got %s, want %s", tc.failures, got, tc.want)
+		}
+	}
+}
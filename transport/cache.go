@@ -0,0 +1,187 @@
+package transport
+
+import (
+	"bytes"
+	"container/list"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// maxCacheBody is the largest response body we will keep in the cache.
+	maxCacheBody = 1 << 20
+	// maxCacheSize is the most the response bodies in the cache can add up
+	// to. The least recently requested responses are dropped to make room.
+	maxCacheSize = 32 << 20
+	// cacheTTL is how long a response is kept in the cache after it was last
+	// requested. It is longer than the intervals the data sources are
+	// refreshed at, so only the responses for resources that are not
+	// requested anymore, ie. for old commits, are dropped.
+	cacheTTL = 3 * time.Hour
+)
+
+// cacheEntry holds a cached response.
+type cacheEntry struct {
+	key    string
+	etag   string
+	header http.Header
+	body   []byte
+	// used is when the response was last requested.
+	used time.Time
+}
+
+// cacheTransport is an http.RoundTripper that caches the responses to GET
+// requests that have an ETag. The next request for the same resource is
+// made with If-None-Match and if the resource has not changed the cached
+// response is returned. GitHub does not count these against the rate limit.
+// The cache is limited in size and drops responses that were not requested
+// for a while, since the dashboard is meant to run for days.
+type cacheTransport struct {
+	rt      http.RoundTripper
+	maxSize int
+	ttl     time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// lru holds the entries with the most recently requested first.
+	lru  *list.List
+	size int
+}
+
+// newCacheTransport returns a cacheTransport keeping at most maxSize bytes
+// of response bodies for at most ttl after they were last requested.
+func newCacheTransport(rt http.RoundTripper, maxSize int, ttl time.Duration) *cacheTransport {
+	return &cacheTransport{
+		rt:      rt,
+		maxSize: maxSize,
+		ttl:     ttl,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return t.rt.RoundTrip(req)
+	}
+
+	// Responses can differ per user so include the credentials in the key.
+	key := req.URL.String() + " " + req.Header.Get("Authorization")
+
+	e := t.get(key)
+
+	if e != nil && req.Header.Get("If-None-Match") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", e.etag)
+	}
+
+	res, err := t.rt.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusNotModified && e != nil {
+		res.Body.Close()
+
+		// Use the new headers so the rate limit headers are current.
+		header := e.header.Clone()
+		for k, v := range res.Header {
+			header[k] = v
+		}
+
+		return &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         res.Proto,
+			ProtoMajor:    res.ProtoMajor,
+			ProtoMinor:    res.ProtoMinor,
+			Header:        header,
+			Body:          ioutil.NopCloser(bytes.NewReader(e.body)),
+			ContentLength: int64(len(e.body)),
+			Request:       res.Request,
+		}, nil
+	}
+
+	etag := res.Header.Get("ETag")
+	if res.StatusCode != http.StatusOK || etag == "" || res.ContentLength > maxCacheBody {
+		return res, nil
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if len(body) <= maxCacheBody {
+		t.put(&cacheEntry{
+			key:    key,
+			etag:   etag,
+			header: res.Header.Clone(),
+			body:   body,
+		})
+	}
+
+	return res, nil
+}
+
+// get returns the cached response for the key, or nil if there is none,
+// marking it as the most recently requested.
+func (t *cacheTransport) get(key string) *cacheEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.expire(now)
+
+	el, ok := t.entries[key]
+	if !ok {
+		return nil
+	}
+	e := el.Value.(*cacheEntry)
+	e.used = now
+	t.lru.MoveToFront(el)
+	return e
+}
+
+// put adds the response to the cache, replacing any cached response for the
+// same key, and drops the least recently requested responses until the
+// cache fits in maxSize.
+func (t *cacheTransport) put(e *cacheEntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if el, ok := t.entries[e.key]; ok {
+		t.remove(el)
+	}
+
+	e.used = time.Now()
+	t.entries[e.key] = t.lru.PushFront(e)
+	t.size += len(e.body)
+
+	for t.size > t.maxSize && t.lru.Len() > 0 {
+		t.remove(t.lru.Back())
+	}
+}
+
+// expire drops the responses that were not requested within the ttl.
+// The cache must be locked.
+func (t *cacheTransport) expire(now time.Time) {
+	for el := t.lru.Back(); el != nil; el = t.lru.Back() {
+		if now.Sub(el.Value.(*cacheEntry).used) < t.ttl {
+			return
+		}
+		t.remove(el)
+	}
+}
+
+// remove drops a response from the cache. The cache must be locked.
+func (t *cacheTransport) remove(el *list.Element) {
+	e := t.lru.Remove(el).(*cacheEntry)
+	delete(t.entries, e.key)
+	t.size -= len(e.body)
+}
@@ -0,0 +1,235 @@
+package transport
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newETagServer returns a test server that responds with the path as the
+// body along with an ETag for it, or a 304 if the request has the ETag. The
+// function returns the requests it got and how many were answered with a
+// 304.
+func newETagServer(t *testing.T) (*httptest.Server, func() (int, int)) {
+	var (
+		mu          sync.Mutex
+		requests    int
+		notModified int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := `"` + r.URL.Path + `"`
+
+		mu.Lock()
+		requests++
+		if r.Method == http.MethodGet && r.Header.Get("If-None-Match") == etag {
+			notModified++
+			mu.Unlock()
+			w.Header().Set("X-RateLimit-Remaining", "41")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		mu.Unlock()
+
+		if r.URL.Query().Get("etag") != "no" {
+			w.Header().Set("ETag", etag)
+		}
+		w.Header().Set("X-RateLimit-Remaining", "42")
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() (int, int) {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests, notModified
+	}
+}
+
+func TestCacheTransport(t *testing.T) {
+	testCases := []struct {
+		name            string
+		method          string
+		path            string
+		auth            []string
+		wantRequests    int
+		wantNotModified int
+	}{
+		{
+			name:            "revalidated",
+			path:            "/repos/a/b",
+			wantRequests:    2,
+			wantNotModified: 1,
+		},
+		{
+			name:         "no etag",
+			path:         "/repos/a/b?etag=no",
+			wantRequests: 2,
+		},
+		{
+			name:         "post",
+			method:       http.MethodPost,
+			path:         "/job/a/build",
+			wantRequests: 2,
+		},
+		{
+			name:         "different credentials",
+			path:         "/repos/a/b",
+			auth:         []string{"token a", "token b"},
+			wantRequests: 2,
+		},
+		{
+			name:            "same credentials",
+			path:            "/repos/a/b",
+			auth:            []string{"token a", "token a"},
+			wantRequests:    2,
+			wantNotModified: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, counts := newETagServer(t)
+			client := NewClient(Options{})
+
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			for i := 0; i < 2; i++ {
+				req, err := http.NewRequest(method, srv.URL+tc.path, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(tc.auth) > i {
+					req.Header.Set("Authorization", tc.auth[i])
+				}
+
+				res, err := client.Do(req)
+				if err != nil {
+					t.Fatalf("request %d failed: %v", i, err)
+				}
+				body, err := ioutil.ReadAll(res.Body)
+				res.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				// A cached response looks just like the original, with
+				// the new headers.
+				if res.StatusCode != http.StatusOK {
+					t.Errorf("request %d: got status %d, want 200", i, res.StatusCode)
+				}
+				if want := strings.SplitN(tc.path, "?", 2)[0]; string(body) != want {
+					t.Errorf("request %d: got body %q, want %q", i, body, want)
+				}
+			}
+
+			requests, notModified := counts()
+			if requests != tc.wantRequests {
+				t.Errorf("got %d requests, want %d", requests, tc.wantRequests)
+			}
+			if notModified != tc.wantNotModified {
+				t.Errorf("got %d not modified responses, want %d", notModified, tc.wantNotModified)
+			}
+		})
+	}
+}
+
+func TestCacheTransportUsesNewHeaders(t *testing.T) {
+	srv, _ := newETagServer(t)
+	client := NewClient(Options{})
+
+	for i, want := range []string{"42", "41"} {
+		res, err := client.Get(srv.URL + "/repos/a/b")
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+		res.Body.Close()
+
+		if got := res.Header.Get("X-RateLimit-Remaining"); got != want {
+			t.Errorf("request %d: got remaining rate limit %q, want %q", i, got, want)
+		}
+		if got := res.Header.Get("ETag"); got != `"/repos/a/b"` {
+			t.Errorf("request %d: got ETag %q, want the cached one", i, got)
+		}
+	}
+}
+
+func TestCacheTransportEviction(t *testing.T) {
+	// Each body is the path, so 6 bytes.
+	testCases := []struct {
+		name    string
+		maxSize int
+		ttl     time.Duration
+		paths   []string
+		want    []string
+	}{
+		{
+			name:    "fits",
+			maxSize: 100,
+			ttl:     time.Hour,
+			paths:   []string{"/aaaaa", "/bbbbb", "/ccccc"},
+			want:    []string{"/ccccc", "/bbbbb", "/aaaaa"},
+		},
+		{
+			name:    "least recently requested dropped",
+			maxSize: 12,
+			ttl:     time.Hour,
+			paths:   []string{"/aaaaa", "/bbbbb", "/aaaaa", "/ccccc"},
+			want:    []string{"/ccccc", "/aaaaa"},
+		},
+		{
+			name:    "too big for the cache",
+			maxSize: 5,
+			ttl:     time.Hour,
+			paths:   []string{"/aaaaa"},
+			want:    []string{},
+		},
+		{
+			name:    "expired",
+			maxSize: 100,
+			ttl:     0,
+			paths:   []string{"/aaaaa", "/bbbbb", "/ccccc"},
+			want:    []string{"/ccccc"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, _ := newETagServer(t)
+			cache := newCacheTransport(http.DefaultTransport, tc.maxSize, tc.ttl)
+			client := &http.Client{Transport: cache}
+
+			for _, path := range tc.paths {
+				res, err := client.Get(srv.URL + path)
+				if err != nil {
+					t.Fatalf("request for %s failed: %v", path, err)
+				}
+				res.Body.Close()
+			}
+
+			got := []string{}
+			size := 0
+			for el := cache.lru.Front(); el != nil; el = el.Next() {
+				e := el.Value.(*cacheEntry)
+				got = append(got, string(e.body))
+				size += len(e.body)
+			}
+			if strings.Join(got, " ") != strings.Join(tc.want, " ") {
+				t.Errorf("got cached %v, want %v", got, tc.want)
+			}
+			if len(cache.entries) != len(tc.want) {
+				t.Errorf("got %d entries, want %d", len(cache.entries), len(tc.want))
+			}
+			if cache.size != size {
+				t.Errorf("got size %d, want %d", cache.size, size)
+			}
+		})
+	}
+}
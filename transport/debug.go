@@ -0,0 +1,160 @@
+package transport
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// redacted replaces secrets in the debug logs.
+	redacted = "REDACTED"
+	// maxLogBody is the most of a request or response body we will log.
+	maxLogBody = 64 << 10
+)
+
+var (
+	// secretHeaders are the headers that are always redacted.
+	secretHeaders = map[string]bool{
+		"Authorization":       true,
+		"Proxy-Authorization": true,
+		"Cookie":              true,
+		"Set-Cookie":          true,
+		"Jenkins-Crumb":       true,
+		"Travis-Api-Token":    true,
+	}
+
+	// secretParams are the query and form parameters and JSON fields that
+	// are redacted.
+	secretParams = []string{
+		"access_token",
+		"refresh_token",
+		"id_token",
+		"assertion",
+		"client_secret",
+		"private_key",
+		"password",
+		"token",
+		"crumb",
+	}
+
+	secretJSON = regexp.MustCompile(`"(` + strings.Join(secretParams, "|") + `)"(\s*:\s*)"[^"]*"`)
+	secretForm = regexp.MustCompile(`(^|&)(` + strings.Join(secretParams, "|") + `)=[^&]*`)
+)
+
+// logTransport is an http.RoundTripper that logs every request and response
+// with the secrets redacted.
+type logTransport struct {
+	rt http.RoundTripper
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *logTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "\n[request %s]\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&buf, "%s %s\n", req.Method, redactURL(req.URL))
+	writeHeader(&buf, req.Header)
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		writeBody(&buf, body)
+	}
+	buf.WriteString("[/request]\n")
+
+	start := time.Now()
+	res, err := t.rt.RoundTrip(req)
+
+	fmt.Fprintf(&buf, "[response %s]\n", time.Since(start))
+	if err != nil {
+		fmt.Fprintf(&buf, "ERROR: %v\n", err)
+	} else {
+		fmt.Fprintf(&buf, "%s %s\n", res.Proto, res.Status)
+		writeHeader(&buf, res.Header)
+		body, rerr := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if rerr != nil {
+			err = rerr
+			res = nil
+			fmt.Fprintf(&buf, "ERROR: reading body failed: %v\n", rerr)
+		} else {
+			res.Body = ioutil.NopCloser(bytes.NewReader(body))
+			writeBody(&buf, body)
+		}
+	}
+	buf.WriteString("[/response]\n")
+
+	// Write each request and response in one go so they don't interleave.
+	t.mu.Lock()
+	t.w.Write(buf.Bytes())
+	t.mu.Unlock()
+
+	return res, err
+}
+
+// redactURL returns the URL with any credentials redacted.
+func redactURL(u *url.URL) string {
+	r := *u
+	if r.User != nil {
+		r.User = url.UserPassword(r.User.Username(), redacted)
+	}
+	r.RawQuery = redactForm(r.RawQuery)
+	return r.String()
+}
+
+// redactForm redacts the secrets in a URL encoded query or form.
+func redactForm(s string) string {
+	return secretForm.ReplaceAllString(s, "${1}${2}="+redacted)
+}
+
+// writeHeader writes the headers sorted by key with the secrets redacted.
+func writeHeader(w io.Writer, h http.Header) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := strings.Join(h[k], ", ")
+		if secretHeaders[http.CanonicalHeaderKey(k)] || strings.Contains(strings.ToLower(k), "token") {
+			v = redacted
+		}
+		fmt.Fprintf(w, "%s: %s\n", k, v)
+	}
+}
+
+// writeBody writes the body with the secrets redacted, truncating it if it
+// is too long.
+func writeBody(w io.Writer, body []byte) {
+	if len(body) <= 0 {
+		return
+	}
+
+	truncated := len(body) > maxLogBody
+	if truncated {
+		body = body[:maxLogBody]
+	}
+
+	s := secretJSON.ReplaceAllString(string(body), `"${1}"${2}"`+redacted+`"`)
+	s = redactForm(s)
+	fmt.Fprintf(w, "\n%s\n", s)
+	if truncated {
+		fmt.Fprintf(w, "... truncated to %d bytes\n", maxLogBody)
+	}
+}
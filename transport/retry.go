@@ -0,0 +1,227 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// minRetryWait is how long to wait before the first retry of a request.
+	// It doubles for each retry after that.
+	minRetryWait = time.Second
+)
+
+// retryTransport is an http.RoundTripper that retries requests that failed
+// with a 5xx or 429 status code or a network error. It waits for the
+// Retry-After header and the GitHub rate limit headers before retrying.
+type retryTransport struct {
+	rt         http.RoundTripper
+	maxRetries int
+	maxWait    time.Duration
+
+	mu sync.Mutex
+	// resets holds when the rate limit resets for the rate limits that
+	// have run out.
+	resets map[string]time.Time
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Wait for the rate limit to reset if we already know it ran out.
+	if err := t.waitRateLimit(req); err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		r := req
+		if attempt > 0 {
+			// Rewind the body for the retry.
+			r = req.Clone(req.Context())
+			if req.Body != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				r.Body = body
+			}
+		}
+
+		res, err := t.rt.RoundTrip(r)
+		t.updateRateLimit(req, res)
+
+		wait, ok := t.shouldRetry(req, res, err, attempt)
+		if !ok {
+			return res, err
+		}
+
+		// Drain the body so the connection can be reused.
+		if res != nil {
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// shouldRetry returns how long to wait before retrying a request and
+// whether it should be retried at all.
+func (t *retryTransport) shouldRetry(req *http.Request, res *http.Response, err error, attempt int) (time.Duration, bool) {
+	if attempt >= t.maxRetries || !replayable(req) || req.Context().Err() != nil {
+		return 0, false
+	}
+
+	// Back off exponentially by default.
+	wait := minRetryWait << uint(attempt)
+
+	switch {
+	case err != nil:
+		// Retry network errors.
+	case res.StatusCode == http.StatusTooManyRequests,
+		res.StatusCode >= 500 && res.StatusCode != http.StatusNotImplemented:
+		if d, ok := retryAfter(res); ok {
+			wait = d
+		} else if reset, ok := rateLimitReset(res); ok {
+			wait = time.Until(reset)
+		}
+	case res.StatusCode == http.StatusForbidden:
+		// GitHub responds with a 403 when the rate limit runs out.
+		reset, ok := rateLimitReset(res)
+		if !ok {
+			return 0, false
+		}
+		wait = time.Until(reset)
+	default:
+		return 0, false
+	}
+
+	if wait > t.maxWait {
+		return 0, false
+	}
+	return wait, true
+}
+
+// waitRateLimit waits for the rate limit of the request to reset if it
+// has run out. If the wait would be longer than maxWait it returns an error
+// rather than making a request we know will fail.
+func (t *retryTransport) waitRateLimit(req *http.Request) error {
+	t.mu.Lock()
+	reset, ok := t.resets[rateLimitKey(req)]
+	t.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	wait := time.Until(reset)
+	if wait <= 0 {
+		return nil
+	}
+	if wait > t.maxWait {
+		return fmt.Errorf("rate limit for %s exceeded until %s", req.URL.Host, reset.Local().Format(time.Kitchen))
+	}
+
+	return sleep(req.Context(), wait)
+}
+
+// updateRateLimit records when the rate limit resets if the response shows
+// it has run out.
+func (t *retryTransport) updateRateLimit(req *http.Request, res *http.Response) {
+	if res == nil {
+		return
+	}
+
+	key := rateLimitKey(req)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if reset, ok := rateLimitReset(res); ok {
+		t.resets[key] = reset
+		return
+	}
+	delete(t.resets, key)
+}
+
+// rateLimitKey returns the key for the rate limit that applies to a request.
+// Rate limits are per host and per user. The GitHub search API has its own
+// rate limit, so running out of it does not hold up the other requests.
+func rateLimitKey(req *http.Request) string {
+	key := req.URL.Host + " " + req.Header.Get("Authorization")
+	if strings.Contains(req.URL.Path, "/search/") {
+		key += " search"
+	}
+	return key
+}
+
+// rateLimitReset returns when the rate limit resets if the GitHub rate limit
+// headers in the response show it has run out.
+func rateLimitReset(res *http.Response) (time.Time, bool) {
+	if res.Header.Get("X-RateLimit-Remaining") != "0" {
+		return time.Time{}, false
+	}
+
+	reset, err := strconv.ParseInt(res.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(reset, 0), true
+}
+
+// retryAfter returns the wait from the Retry-After header in the response,
+// which can either be in seconds or a date.
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	v := res.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+
+	if s, err := strconv.Atoi(v); err == nil {
+		return time.Duration(s) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t), true
+	}
+
+	return 0, false
+}
+
+// replayable returns true if it is safe to send the request again.
+// Only idempotent requests are retried so we never trigger a build twice.
+func replayable(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		return false
+	}
+
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// sleep waits for d or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
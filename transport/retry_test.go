@@ -0,0 +1,343 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// response describes a response for a test server to send.
+type response struct {
+	status int
+	header map[string]string
+}
+
+// newSequenceServer returns a test server that sends the responses in order,
+// repeating the last one, and a function returning how many requests it got.
+func newSequenceServer(t *testing.T, responses []response) (*httptest.Server, func() int) {
+	var (
+		mu    sync.Mutex
+		count int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		i := count
+		count++
+		mu.Unlock()
+
+		if i >= len(responses) {
+			i = len(responses) - 1
+		}
+		for k, v := range responses[i].header {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(responses[i].status)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return count
+	}
+}
+
+func TestRetryTransport(t *testing.T) {
+	// Retry-After: 0 keeps the retries from backing off.
+	now := map[string]string{"Retry-After": "0"}
+	past := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+	future := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+
+	testCases := []struct {
+		name         string
+		method       string
+		maxRetries   int
+		responses    []response
+		wantStatus   int
+		wantRequests int
+	}{
+		{
+			name:         "success",
+			maxRetries:   3,
+			responses:    []response{{status: 200}},
+			wantStatus:   200,
+			wantRequests: 1,
+		},
+		{
+			name:         "server error then success",
+			maxRetries:   3,
+			responses:    []response{{status: 502, header: now}, {status: 200}},
+			wantStatus:   200,
+			wantRequests: 2,
+		},
+		{
+			name:         "too many requests then success",
+			maxRetries:   3,
+			responses:    []response{{status: 429, header: now}, {status: 200}},
+			wantStatus:   200,
+			wantRequests: 2,
+		},
+		{
+			name:         "out of retries",
+			maxRetries:   2,
+			responses:    []response{{status: 500, header: now}},
+			wantStatus:   500,
+			wantRequests: 3,
+		},
+		{
+			name:         "retries turned off",
+			maxRetries:   0,
+			responses:    []response{{status: 500, header: now}},
+			wantStatus:   500,
+			wantRequests: 1,
+		},
+		{
+			name:         "not found is not retried",
+			maxRetries:   3,
+			responses:    []response{{status: 404}},
+			wantStatus:   404,
+			wantRequests: 1,
+		},
+		{
+			name:         "not implemented is not retried",
+			maxRetries:   3,
+			responses:    []response{{status: 501, header: now}},
+			wantStatus:   501,
+			wantRequests: 1,
+		},
+		{
+			name:         "post is not retried",
+			method:       http.MethodPost,
+			maxRetries:   3,
+			responses:    []response{{status: 503, header: now}, {status: 200}},
+			wantStatus:   503,
+			wantRequests: 1,
+		},
+		{
+			name:       "rate limit that reset is retried",
+			maxRetries: 3,
+			responses: []response{
+				{status: 403, header: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": past}},
+				{status: 200},
+			},
+			wantStatus:   200,
+			wantRequests: 2,
+		},
+		{
+			name:       "rate limit longer than the max wait is not retried",
+			maxRetries: 3,
+			responses: []response{
+				{status: 403, header: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": future}},
+				{status: 200},
+			},
+			wantStatus:   403,
+			wantRequests: 1,
+		},
+		{
+			name:         "forbidden is not retried",
+			maxRetries:   3,
+			responses:    []response{{status: 403}, {status: 200}},
+			wantStatus:   403,
+			wantRequests: 1,
+		},
+		{
+			name:         "retry after longer than the max wait is not retried",
+			maxRetries:   3,
+			responses:    []response{{status: 503, header: map[string]string{"Retry-After": "3600"}}, {status: 200}},
+			wantStatus:   503,
+			wantRequests: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, requests := newSequenceServer(t, tc.responses)
+
+			client := NewClient(Options{
+				Timeout:    10 * time.Second,
+				MaxRetries: tc.maxRetries,
+				MaxWait:    time.Minute,
+			})
+
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			// The body can be rewound, but a POST is still never retried.
+			req, err := http.NewRequest(method, srv.URL, strings.NewReader("build=1"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			res.Body.Close()
+
+			if res.StatusCode != tc.wantStatus {
+				t.Errorf("got status %d, want %d", res.StatusCode, tc.wantStatus)
+			}
+			if got := requests(); got != tc.wantRequests {
+				t.Errorf("got %d requests, want %d", got, tc.wantRequests)
+			}
+		})
+	}
+}
+
+func TestRetryTransportWaitsOutRateLimit(t *testing.T) {
+	reset := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	srv, requests := newSequenceServer(t, []response{
+		{status: 200, header: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset}},
+	})
+
+	client := NewClient(Options{MaxWait: time.Minute})
+
+	res, err := client.Get(srv.URL + "/repos")
+	if err != nil {
+		t.Fatalf("first request failed: %v", err)
+	}
+	res.Body.Close()
+
+	// The rate limit ran out, so the next request fails without being made.
+	if _, err := client.Get(srv.URL + "/users"); err == nil || !strings.Contains(err.Error(), "rate limit") {
+		t.Errorf("got error %v, want a rate limit error", err)
+	}
+	if got := requests(); got != 1 {
+		t.Errorf("got %d requests, want 1", got)
+	}
+
+	// The search API has its own rate limit.
+	res, err = client.Get(srv.URL + "/search/issues")
+	if err != nil {
+		t.Fatalf("search request failed: %v", err)
+	}
+	res.Body.Close()
+	if got := requests(); got != 2 {
+		t.Errorf("got %d requests, want 2", got)
+	}
+}
+
+func TestRetryTransportContextCanceled(t *testing.T) {
+	srv, requests := newSequenceServer(t, []response{{status: 503, header: map[string]string{"Retry-After": "30"}}})
+
+	client := NewClient(Options{MaxRetries: 3, MaxWait: time.Minute})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Do(req); err == nil {
+		t.Error("expected an error once the context was done")
+	}
+	if got := requests(); got != 1 {
+		t.Errorf("got %d requests, want 1", got)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	testCases := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "missing"},
+		{name: "seconds", value: "120", want: 2 * time.Minute, wantOK: true},
+		{name: "zero", value: "0", want: 0, wantOK: true},
+		{name: "date", value: time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), want: time.Hour, wantOK: true},
+		{name: "invalid", value: "soon"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := &http.Response{Header: http.Header{}}
+			if tc.value != "" {
+				res.Header.Set("Retry-After", tc.value)
+			}
+
+			got, ok := retryAfter(res)
+			if ok != tc.wantOK {
+				t.Fatalf("got ok %t, want %t", ok, tc.wantOK)
+			}
+			// Allow for the time passing and the date being in seconds.
+			if diff := got - tc.want; diff > 0 || diff < -2*time.Second {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitReset(t *testing.T) {
+	testCases := []struct {
+		name      string
+		remaining string
+		reset     string
+		want      time.Time
+		wantOK    bool
+	}{
+		{name: "no headers"},
+		{name: "remaining", remaining: "10", reset: "1600000000"},
+		{name: "ran out", remaining: "0", reset: "1600000000", want: time.Unix(1600000000, 0), wantOK: true},
+		{name: "ran out with an invalid reset", remaining: "0", reset: "later"},
+		{name: "ran out without a reset", remaining: "0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := &http.Response{Header: http.Header{}}
+			if tc.remaining != "" {
+				res.Header.Set("X-RateLimit-Remaining", tc.remaining)
+			}
+			if tc.reset != "" {
+				res.Header.Set("X-RateLimit-Reset", tc.reset)
+			}
+
+			got, ok := rateLimitReset(res)
+			if ok != tc.wantOK {
+				t.Fatalf("got ok %t, want %t", ok, tc.wantOK)
+			}
+			if !got.Equal(tc.want) {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitKey(t *testing.T) {
+	testCases := []struct {
+		name string
+		url  string
+		auth string
+		want string
+	}{
+		{name: "anonymous", url: "https://api.github.com/repos/a/b", want: "api.github.com "},
+		{name: "token", url: "https://api.github.com/repos/a/b", auth: "token x", want: "api.github.com token x"},
+		{name: "search", url: "https://api.github.com/search/issues?q=x", auth: "token x", want: "api.github.com token x search"},
+		{name: "enterprise search", url: "https://github.example.com/api/v3/search/issues", want: "github.example.com  search"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+
+			if got := rateLimitKey(req); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
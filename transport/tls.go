@@ -0,0 +1,69 @@
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// TLSOptions holds the TLS settings for connecting to a server that is not
+// signed by a public CA or requires a client certificate.
+type TLSOptions struct {
+	// CAFile is the path to a PEM bundle of CA certificates to trust as well
+	// as the system ones.
+	CAFile string
+	// CertFile and KeyFile are the paths to the PEM client certificate and
+	// key to authenticate with.
+	CertFile string
+	KeyFile  string
+	// InsecureSkipVerify turns off verification of the server certificate.
+	// It should only be used for test servers.
+	InsecureSkipVerify bool
+}
+
+// IsZero returns true if no TLS settings are given.
+func (o TLSOptions) IsZero() bool {
+	return o == TLSOptions{}
+}
+
+// NewTLSTransport returns a base transport with the same settings as
+// http.DefaultTransport that uses the TLS settings.
+func NewTLSTransport(opts TLSOptions) (*http.Transport, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+
+	if len(opts.CAFile) > 0 {
+		pem, err := ioutil.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA file %q failed: %v", opts.CAFile, err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %q", opts.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if len(opts.CertFile) > 0 || len(opts.KeyFile) > 0 {
+		if len(opts.CertFile) <= 0 || len(opts.KeyFile) <= 0 {
+			return nil, fmt.Errorf("both a client certificate and key file are required")
+		}
+
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate %q failed: %v", opts.CertFile, err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = config
+	return t, nil
+}
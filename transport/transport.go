@@ -0,0 +1,61 @@
+// Package transport provides the HTTP client shared by all the data sources.
+// It retries failed requests with a backoff, respects the Retry-After and
+// GitHub rate limit headers, caches responses with an ETag so unchanged
+// resources are fetched with conditional requests, and can log every request
+// and response for debugging.
+package transport
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// Options holds the options for the shared HTTP client.
+type Options struct {
+	// Timeout is the time limit for each request, including retries.
+	// Zero means no timeout.
+	Timeout time.Duration
+	// MaxRetries is the number of times to retry a request that failed with
+	// a 5xx or 429 status code or a network error.
+	MaxRetries int
+	// MaxWait is the longest we will wait before retrying a request because
+	// of a Retry-After or rate limit header. If the wait would be longer
+	// the response is returned as is.
+	MaxWait time.Duration
+	// Base is the transport used to make the requests.
+	// If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+	// Debug is where every request and response is logged, with the
+	// secrets redacted. If nil, nothing is logged.
+	Debug io.Writer
+}
+
+// NewClient returns an HTTP client with the transport stack for the given
+// options.
+func NewClient(opts Options) *http.Client {
+	return &http.Client{
+		Timeout:   opts.Timeout,
+		Transport: New(opts),
+	}
+}
+
+// New returns the transport stack for the given options.
+// Requests go through the ETag cache, then the retries, then the debug
+// logging, then the base transport.
+func New(opts Options) http.RoundTripper {
+	base := opts.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if opts.Debug != nil {
+		base = &logTransport{rt: base, w: opts.Debug}
+	}
+
+	return newCacheTransport(&retryTransport{
+		rt:         base,
+		maxRetries: opts.MaxRetries,
+		maxWait:    opts.MaxWait,
+		resets:     map[string]time.Time{},
+	}, maxCacheSize, cacheTTL)
+}
@@ -3,100 +3,350 @@ package main
 import (
 	"context"
 	"fmt"
-	"net/http"
+	"io"
+	"strings"
+	"sync"
 	"time"
 
-	travis "github.com/Ableton/go-travis"
 	"github.com/gizak/termui"
-	"github.com/google/go-github/github"
+	"github.com/jessfraz/tdash/travis"
 	"github.com/sirupsen/logrus"
 )
 
-func doTravisCI() ([]*termui.Table, error) {
+func init() {
+	registerSource("travis", func() Source { return &travisSource{} })
+}
+
+// travisBuild holds the build status for a branch of a Travis CI repository.
+type travisBuild struct {
+	repo       string
+	branch     string
+	number     string
+	state      string
+	duration   time.Duration
+	sha        string
+	message    string
+	committer  string
+	finishedAt string
+	// failedJobs holds the names of the jobs in the build matrix that
+	// failed, if the build failed, and failedJob the ID of the first one.
+	failedJobs []string
+	failedJob  int
+}
+
+// key returns the key the build is selected by.
+func (b travisBuild) key(owner string) string {
+	return owner + "/" + b.repo + "@" + b.branch
+}
+
+// travisData holds the Travis CI builds for an owner.
+type travisData struct {
+	owner  string
+	builds []travisBuild
+}
+
+// travisOwner holds the settings for an owner of Travis CI repositories.
+// In the config file it is either the name of the owner or a mapping.
+type travisOwner struct {
+	Name string `yaml:"name"`
+	// Branches are the branches to show for the repositories of the owner.
+	Branches []string `yaml:"branches"`
+	// Repos holds the branches to show for specific repositories of the
+	// owner by their name.
+	Repos map[string][]string `yaml:"repos"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (o *travisOwner) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&o.Name); err == nil {
+		return nil
+	}
+
+	type plain travisOwner
+	return unmarshal((*plain)(o))
+}
+
+// travisSettings holds the settings for a Travis CI data source.
+// The branches shown for a repository are the ones set for it, then the ones
+// set for its owner, then the ones set for the data source, and if none are
+// set, the default branch of the repository.
+type travisSettings struct {
+	// Endpoint is the Travis CI API endpoint, ie. https://api.travis-ci.com
+	// or the API of a Travis CI Enterprise install.
+	Endpoint string        `yaml:"endpoint"`
+	Token    string        `yaml:"token"`
+	Owners   []travisOwner `yaml:"owners"`
+	Branches []string      `yaml:"branches"`
+	// Concurrency is the number of repositories to get the build status for
+	// at once.
+	Concurrency int `yaml:"concurrency"`
+}
+
+// travisSource is a Source for Travis CI builds.
+type travisSource struct {
+	settings travisSettings
+	client   *travis.Client
+}
+
+// Name implements Source.
+func (s *travisSource) Name() string {
+	return "Travis CI"
+}
+
+// Configure implements Source.
+func (s *travisSource) Configure(cfg *sourceConfig) (bool, error) {
+	// Get the settings from the flags and config file.
+	s.settings = travisSettings{
+		Endpoint:    travisEndpoint,
+		Token:       travisToken,
+		Owners:      travisOwnersFromFlags(),
+		Concurrency: 8,
+	}
+	if err := cfg.decode(&s.settings); err != nil {
+		return false, err
+	}
+	if flagPassed("travis-endpoint") {
+		s.settings.Endpoint = travisEndpoint
+	}
+	if flagPassed("travis-token") {
+		s.settings.Token = travisToken
+	}
+	if flagPassed("travis-owner") {
+		s.settings.Owners = travisOwnersFromFlags()
+	}
+
 	// Check that the Travis CI API token is not empty.
-	if len(travisToken) <= 0 {
+	if len(s.settings.Token) <= 0 {
 		logrus.Warn("Travis CI API token cannot be empty")
-		logrus.Info("skipping Travis CI data")
-		return nil, nil
+		return false, nil
 	}
 
 	// Check that the Travis owners is not empty.
-	if len(travisOwners) <= 0 {
+	if len(s.settings.Owners) <= 0 {
 		logrus.Warn("Travis CI owners cannot be empty")
-		logrus.Info("skipping Travis CI data")
-		return nil, nil
+		return false, nil
+	}
+	for i, owner := range s.settings.Owners {
+		if len(owner.Name) <= 0 {
+			return false, fmt.Errorf("travis owner %d is missing a name", i)
+		}
 	}
 
-	tables := []*termui.Table{}
+	if s.settings.Concurrency <= 0 {
+		s.settings.Concurrency = 1
+	}
 
-	// Iterate over the travisOwners if it was passed.
-	for _, travisOwner := range travisOwners {
-		// Initialize the table.
-		table := termui.NewTable()
-		rows := [][]string{
-			{"repo", "branch", "state", "finished at"},
+	// Initialize the travis api client
+	s.client = travis.New(s.settings.Endpoint, s.settings.Token, httpClient)
+
+	return true, nil
+}
+
+// travisOwnersFromFlags returns the owners passed on the command line.
+func travisOwnersFromFlags() []travisOwner {
+	owners := []travisOwner{}
+	for _, name := range travisOwners {
+		owners = append(owners, travisOwner{Name: name})
+	}
+	return owners
+}
+
+// branches returns the branches to show for the repository.
+func (s *travisSource) branches(owner travisOwner, repo travis.Repository) []string {
+	if branches, ok := owner.Repos[repo.Name]; ok {
+		return branches
+	}
+	if len(owner.Branches) > 0 {
+		return owner.Branches
+	}
+	if len(s.settings.Branches) > 0 {
+		return s.settings.Branches
+	}
+	if len(repo.DefaultBranch.Name) > 0 {
+		return []string{repo.DefaultBranch.Name}
+	}
+	return []string{"master"}
+}
+
+// Fetch implements Source.
+func (s *travisSource) Fetch(ctx context.Context) (interface{}, error) {
+	data := []travisData{}
+
+	// Iterate over the owners.
+	for _, owner := range s.settings.Owners {
+		travisOwner := owner.Name
+		d := travisData{owner: travisOwner}
+
+		// Get the owners active repos from Travis.
+		repos, err := s.client.ListRepos(ctx, travisOwner)
+		if err != nil {
+			return nil, fmt.Errorf("listing travis repos for %q failed: %v", travisOwner, err)
 		}
-		redrows := []int{}
-		otherrows := []int{}
 
-		// Get the owners repos from GitHub.
-		ghClient := github.NewClient(nil)
-		opt := &github.RepositoryListOptions{
-			ListOptions: github.ListOptions{PerPage: 100},
-			Type:        "sources",
-		}
-		var repos []*github.Repository
-		for {
-			reposResp, resp, err := ghClient.Repositories.List(context.Background(), travisOwner, opt)
-			if err != nil {
-				return nil, fmt.Errorf("listing repos for %q failed: %v", travisOwner, err)
-			}
-			repos = append(repos, reposResp...)
-			if resp.NextPage == 0 {
-				break
+		// Get the build status of the branches for the repositories,
+		// at most s.settings.Concurrency at a time.
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			lastErr error
+		)
+		builds := make([][]travisBuild, len(repos))
+		sem := make(chan struct{}, s.settings.Concurrency)
+		for i, repo := range repos {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				wg.Wait()
+				return nil, ctx.Err()
 			}
-			opt.Page = resp.NextPage
+
+			wg.Add(1)
+			go func(i int, repo travis.Repository) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+
+				for _, branch := range s.branches(owner, repo) {
+					build, err := s.getBuild(ctx, repo, branch)
+					if err != nil {
+						mu.Lock()
+						lastErr = err
+						mu.Unlock()
+						return
+					}
+					if build != nil {
+						builds[i] = append(builds[i], *build)
+					}
+				}
+			}(i, repo)
 		}
+		wg.Wait()
 
-		// Initialize the travis client.
-		travisClient := travis.NewClient(travis.TRAVIS_API_DEFAULT_URL, travisToken)
+		if lastErr != nil {
+			return nil, lastErr
+		}
 
-		// Iterate over the repositories and get the master branch build status.
-		for _, repo := range repos {
-			if repo.GetFork() || repo.GetArchived() {
-				// Continue early if its a fork or archived because we don't care.
-				continue
-			}
+		// Add the builds in the same order as the repositories.
+		for _, b := range builds {
+			d.builds = append(d.builds, b...)
+		}
 
-			// Get the master branch
-			branch, resp, err := travisClient.Branches.GetFromSlug(repo.GetFullName(), "master")
-			if err != nil {
-				// This will fail on forks or non travis building repos with a 404
-				// so we might as well error silently if we get a 404.
-				if resp.StatusCode == http.StatusNotFound {
-					continue
+		data = append(data, d)
+	}
+
+	return data, nil
+}
+
+// getBuild returns the build status of a branch of a repository.
+// It returns nil if the repository does not build on Travis CI or the branch
+// was never built.
+func (s *travisSource) getBuild(ctx context.Context, repo travis.Repository, branchName string) (*travisBuild, error) {
+	// Get the branch
+	branch, err := s.client.GetBranch(ctx, repo.Slug, branchName)
+	if err != nil {
+		// This will fail for branches that do not exist with a 404 so we
+		// might as well error silently if we get a 404.
+		if travis.IsNotFound(err) {
+			logrus.Debugf("no travis branch %q for %q", branchName, repo.Slug)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("getting %s branch for travis repo %q failed: %v", branchName, repo.Slug, err)
+	}
+	if branch.LastBuild == nil {
+		// The branch was never built.
+		return nil, nil
+	}
+
+	b := branch.LastBuild
+	build := &travisBuild{
+		repo:       repo.Name,
+		branch:     branchName,
+		number:     b.Number,
+		state:      b.State,
+		duration:   time.Duration(b.Duration) * time.Second,
+		finishedAt: b.FinishedAt,
+	}
+	if b.Commit != nil {
+		build.sha = b.Commit.SHA
+		// Only keep the subject of the commit message.
+		build.message = strings.SplitN(b.Commit.Message, "\n", 2)[0]
+		build.committer = b.Commit.Committer.Name
+		if len(build.committer) <= 0 {
+			build.committer = b.Commit.Author.Name
+		}
+	}
+	if len(build.committer) <= 0 && b.CreatedBy != nil {
+		build.committer = b.CreatedBy.Login
+	}
+
+	// Get which jobs in the build matrix failed.
+	if b.State == "failed" || b.State == "errored" {
+		jobs, err := s.client.GetJobs(ctx, b.ID)
+		if err != nil {
+			return nil, fmt.Errorf("getting jobs for travis build %d of %q failed: %v", b.ID, repo.Slug, err)
+		}
+		for _, job := range jobs {
+			if job.Failed() {
+				build.failedJobs = append(build.failedJobs, job.Name())
+				if build.failedJob == 0 {
+					build.failedJob = job.ID
 				}
-				return nil, fmt.Errorf("getting master branch for travis repo %q failed: %v", repo.GetFullName(), err)
 			}
+		}
+	}
+
+	return build, nil
+}
+
+// Render implements Source.
+func (s *travisSource) Render(v interface{}) []*termui.Row {
+	tables := []termui.GridBufferer{}
+
+	for _, d := range v.([]travisData) {
+		// Initialize the table.
+		table := termui.NewTable()
+		rows := [][]string{
+			{"repo", "branch", "build", "state", "duration", "commit", "committer", "finished at", "failed jobs"},
+		}
+		redrows := []int{}
+		otherrows := []int{}
+		selected := selectedKey(s)
+		selectedRow := -1
+
+		for _, build := range d.builds {
+			if showAllBuilds || build.state != "passed" {
+				duration := ""
+				if build.duration > 0 {
+					duration = printDuration(build.duration)
+				}
 
-			if showAllBuilds || branch.State != "passed" {
 				rows = append(rows, []string{
-					repo.GetName(),
-					"master",
-					branch.State,
-					printTime(branch.FinishedAt),
+					build.repo,
+					build.branch,
+					"#" + build.number,
+					build.state,
+					duration,
+					printCommit(build.sha, build.message),
+					build.committer,
+					printTime(build.finishedAt),
+					strings.Join(build.failedJobs, ", "),
 				})
 
-				if branch.State == "failed" {
+				if build.key(d.owner) == selected {
+					selectedRow = len(rows) - 1
+				}
+
+				if build.state == "failed" {
 					redrows = append(redrows, len(rows)-1)
-				} else if branch.State != "passed" {
+				} else if build.state != "passed" {
 					otherrows = append(otherrows, len(rows)-1)
 				}
 			}
 		}
 
 		if len(rows) <= 1 {
-			// return early if we have no data
+			// continue early if we have no data
 			continue
 		}
 
@@ -109,7 +359,7 @@ func doTravisCI() ([]*termui.Table, error) {
 		table.TextAlign = termui.AlignLeft
 		table.Border = true
 		table.Separator = true
-		table.Block.BorderLabel = "Travis CI builds for " + travisOwner
+		table.Block.BorderLabel = "Travis CI builds for " + d.owner
 		table.Analysis()
 		table.SetSize()
 		// Set the color to red for the red rows
@@ -120,11 +370,90 @@ func doTravisCI() ([]*termui.Table, error) {
 		for _, br := range otherrows {
 			table.FgColors[br] = termui.ColorYellow
 		}
+		if selectedRow > 0 {
+			highlightRow(table, selectedRow)
+		}
 
 		tables = append(tables, table)
 	}
 
-	return tables, nil
+	return columnRows(tables)
+}
+
+// Items implements Selectable. The builds are selected by the owner, repo
+// and branch.
+func (s *travisSource) Items(v interface{}) []string {
+	keys := []string{}
+	for _, d := range v.([]travisData) {
+		for _, build := range d.builds {
+			if showAllBuilds || build.state != "passed" {
+				keys = append(keys, build.key(d.owner))
+			}
+		}
+	}
+	return keys
+}
+
+// Detail implements Selectable. It shows the build with its commit and the
+// jobs that failed.
+func (s *travisSource) Detail(v interface{}, key string) []*termui.Row {
+	build, ok := lookupTravisBuild(v, key)
+	if !ok {
+		return nil
+	}
+
+	items := []string{
+		fmt.Sprintf("build #%s %s at %s", build.number, build.state, printTime(build.finishedAt)),
+	}
+	if len(build.sha) > 0 {
+		items = append(items, fmt.Sprintf("commit %s by %s: %s", build.sha, build.committer, build.message))
+	}
+	if len(build.failedJobs) > 0 {
+		items = append(items, "failed jobs: "+strings.Join(build.failedJobs, ", "))
+	}
+	if build.failedJob > 0 {
+		items = append(items, "keys: l log of the first failed job")
+	}
+
+	list := termui.NewList()
+	list.Items = items
+	list.ItemFgColor = termui.ColorWhite
+	list.BorderLabel = fmt.Sprintf("%s on %s", build.repo, build.branch)
+	list.Height = len(items) + 2
+	if build.state == "failed" {
+		list.BorderFg = termui.ColorRed
+	} else if build.state != "passed" {
+		list.BorderFg = termui.ColorYellow
+	}
+
+	return []*termui.Row{termui.NewRow(termui.NewCol(12, 0, list))}
+}
+
+// Log implements Logger. It streams the log of the first failed job of the
+// build.
+func (s *travisSource) Log(v interface{}, key string) (string, logStreamer) {
+	build, ok := lookupTravisBuild(v, key)
+	if !ok || build.failedJob <= 0 {
+		return "", nil
+	}
+
+	client, job := s.client, build.failedJob
+	title := fmt.Sprintf("Log for %s on %s #%s", build.repo, build.branch, build.number)
+	return title, func(ctx context.Context, w io.Writer) error {
+		return client.StreamLog(ctx, job, w)
+	}
+}
+
+// lookupTravisBuild returns the build with the key from the data.
+func lookupTravisBuild(v interface{}, key string) (travisBuild, bool) {
+	for _, d := range v.([]travisData) {
+		for _, build := range d.builds {
+			if build.key(d.owner) == key {
+				return build, true
+			}
+		}
+	}
+	return travisBuild{}, false
 }
 
 func printTime(s string) string {
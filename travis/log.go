@@ -0,0 +1,166 @@
+package travis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const (
+	// logPollInterval is how often the log of a running job is polled for
+	// more output.
+	logPollInterval = 3 * time.Second
+	// logTailSize is how much of the end of the log of a job is shown, so a
+	// huge log is not pulled down in one request.
+	logTailSize = 256 << 10
+)
+
+// GetJob gets a job.
+func (c *Client) GetJob(ctx context.Context, jobID int) (*Job, error) {
+	var j Job
+	if err := c.get(ctx, fmt.Sprintf("/job/%d", jobID), &j); err != nil {
+		return nil, err
+	}
+
+	return &j, nil
+}
+
+// Finished returns true if the job is done running.
+func (j Job) Finished() bool {
+	switch j.State {
+	case "passed", "failed", "errored", "canceled":
+		return true
+	}
+	return false
+}
+
+// StreamLog writes the tail of the log of a job to w as it is logged. Only
+// what was logged since the last poll is requested each time. It returns
+// once the job has finished and all of its log was written, or the context
+// is done.
+func (c *Client) StreamLog(ctx context.Context, jobID int, w io.Writer) error {
+	// Start from the tail of the log.
+	start := int64(-logTailSize)
+	for {
+		// Get the state before the log, so we do not miss the end of the
+		// log if the job finishes in between.
+		job, err := c.GetJob(ctx, jobID)
+		if err != nil {
+			return err
+		}
+
+		text, offset, err := c.getLogText(ctx, jobID, start)
+		if err != nil {
+			return err
+		}
+		next := offset + int64(len(text))
+
+		if start < 0 && offset > 0 {
+			fmt.Fprintf(w, "... skipped the first %d KB of the log\n", offset>>10)
+			// The tail starts in the middle of a line, so drop it.
+			text = text[bytes.IndexByte(text, '\n')+1:]
+		}
+		if _, err := w.Write(text); err != nil {
+			return err
+		}
+		start = next
+
+		if job.Finished() {
+			return nil
+		}
+
+		select {
+		case <-time.After(logPollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// getLogText gets the plain text log of a job from the offset start on, or
+// if start is negative, its last -start bytes. It returns the text and the
+// offset in the log it starts at. If the API does not support range
+// requests, the whole log is returned by it and cut down to what was asked
+// for.
+func (c *Client) getLogText(ctx context.Context, jobID int, start int64) ([]byte, int64, error) {
+	path := fmt.Sprintf("/job/%d/log.txt", jobID)
+
+	// set up the request
+	req, err := http.NewRequestWithContext(ctx, "GET", c.Endpoint+path, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Travis-API-Version", "3")
+	req.Header.Set("Accept", "text/plain")
+	if len(c.Token) > 0 {
+		req.Header.Set("Authorization", "token "+c.Token)
+	}
+	if start < 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d", start))
+	} else {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", start))
+	}
+
+	// do the request
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Nothing was logged after start.
+		if start < 0 {
+			start = 0
+		}
+		return nil, start, nil
+	case http.StatusPartialContent:
+		offset, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil {
+			return nil, 0, fmt.Errorf("log from %s has %v", path, err)
+		}
+		text, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, 0, fmt.Errorf("reading log from %s failed: %v", path, err)
+		}
+		return text, offset, nil
+	case http.StatusOK:
+		text, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, 0, fmt.Errorf("reading log from %s failed: %v", path, err)
+		}
+		offset := start
+		if start < 0 {
+			offset = int64(len(text)) + start
+		}
+		if offset < 0 {
+			offset = 0
+		}
+		if offset > int64(len(text)) {
+			offset = int64(len(text))
+		}
+		return text[offset:], offset, nil
+	}
+
+	e := &Error{StatusCode: resp.StatusCode}
+	// The error body is only used for the message, so ignore it if it is
+	// not json.
+	json.NewDecoder(resp.Body).Decode(e)
+	return nil, 0, e
+}
+
+// parseContentRange returns the offset of the first byte from a
+// Content-Range header, ie. bytes 100-199/1000.
+func parseContentRange(s string) (int64, error) {
+	var first int64
+	if _, err := fmt.Sscanf(s, "bytes %d-", &first); err != nil {
+		return 0, fmt.Errorf("an invalid Content-Range header %q", s)
+	}
+	return first, nil
+}
@@ -0,0 +1,83 @@
+package travis
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newLogServer returns a client for a test Travis CI API with a finished job
+// that logged log. If ranges is false, the API ignores range requests.
+func newLogServer(t *testing.T, log string, ranges bool) *Client {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/job/1":
+			fmt.Fprint(w, `{"id":1,"state":"failed"}`)
+		case "/job/1/log.txt":
+			if !ranges {
+				fmt.Fprint(w, log)
+				return
+			}
+			http.ServeContent(w, r, "log.txt", time.Time{}, strings.NewReader(log))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return New(srv.URL, "", srv.Client())
+}
+
+func TestGetLogText(t *testing.T) {
+	log := strings.Repeat("0123456789", 10)
+
+	testCases := []struct {
+		name       string
+		start      int64
+		wantText   string
+		wantOffset int64
+	}{
+		{name: "tail", start: -10, wantText: log[90:], wantOffset: 90},
+		{name: "tail of a short log", start: -200, wantText: log},
+		{name: "appended", start: 50, wantText: log[50:], wantOffset: 50},
+		{name: "nothing appended", start: 100, wantOffset: 100},
+	}
+
+	for _, ranges := range []bool{true, false} {
+		c := newLogServer(t, log, ranges)
+		for _, tc := range testCases {
+			t.Run(fmt.Sprintf("%s/ranges=%t", tc.name, ranges), func(t *testing.T) {
+				text, offset, err := c.getLogText(context.Background(), 1, tc.start)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(text) != tc.wantText || offset != tc.wantOffset {
+					t.Errorf("got %q at %d, want %q at %d", text, offset, tc.wantText, tc.wantOffset)
+				}
+			})
+		}
+	}
+}
+
+func TestStreamLogTail(t *testing.T) {
+	line := strings.Repeat("x", 99) + "\n"
+	log := strings.Repeat(line, 3000)
+
+	var buf bytes.Buffer
+	if err := newLogServer(t, log, true).StreamLog(context.Background(), 1, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// The tail starts in the middle of a line, which is dropped.
+	skipped := len(log) - logTailSize
+	tail := log[skipped:]
+	want := fmt.Sprintf("... skipped the first %d KB of the log\n", skipped>>10) + tail[strings.Index(tail, "\n")+1:]
+	if buf.String() != want {
+		t.Errorf("got %d bytes starting with %q, want %d bytes", buf.Len(), buf.String()[:60], len(want))
+	}
+}
@@ -0,0 +1,246 @@
+// Package travis is a client for the Travis CI API v3.
+// See https://developer.travis-ci.com.
+package travis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultEndpoint is the endpoint for the Travis CI API on travis-ci.com.
+const DefaultEndpoint = "https://api.travis-ci.com"
+
+// Client contains the information for connecting to the Travis CI API.
+type Client struct {
+	Endpoint string
+	Token    string
+
+	httpClient *http.Client
+}
+
+// Repository describes a repository from the Travis CI API.
+type Repository struct {
+	ID            int    `json:"id,omitempty"`
+	Name          string `json:"name,omitempty"`
+	Slug          string `json:"slug,omitempty"`
+	Active        bool   `json:"active,omitempty"`
+	Private       bool   `json:"private,omitempty"`
+	DefaultBranch Branch `json:"default_branch,omitempty"`
+}
+
+// Branch describes a branch of a repository from the Travis CI API.
+type Branch struct {
+	Name      string `json:"name,omitempty"`
+	LastBuild *Build `json:"last_build,omitempty"`
+}
+
+// Build describes a build from the Travis CI API.
+type Build struct {
+	ID     int    `json:"id,omitempty"`
+	Number string `json:"number,omitempty"`
+	// State is one of created, received, started, passed, failed, errored
+	// or canceled.
+	State string `json:"state,omitempty"`
+	// Duration is how long the build took in seconds.
+	Duration   int    `json:"duration,omitempty"`
+	EventType  string `json:"event_type,omitempty"`
+	StartedAt  string `json:"started_at,omitempty"`
+	FinishedAt string `json:"finished_at,omitempty"`
+	// Commit and CreatedBy are only set for the last build of a branch.
+	Commit    *Commit `json:"commit,omitempty"`
+	CreatedBy *User   `json:"created_by,omitempty"`
+}
+
+// Commit describes the commit a build ran for.
+type Commit struct {
+	SHA       string `json:"sha,omitempty"`
+	Ref       string `json:"ref,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Author    Person `json:"author,omitempty"`
+	Committer Person `json:"committer,omitempty"`
+}
+
+// Person describes the author or committer of a commit.
+type Person struct {
+	Name string `json:"name,omitempty"`
+}
+
+// User describes a Travis CI user.
+type User struct {
+	Login string `json:"login,omitempty"`
+}
+
+// Job describes a job in the build matrix of a build.
+type Job struct {
+	ID     int    `json:"id,omitempty"`
+	Number string `json:"number,omitempty"`
+	State  string `json:"state,omitempty"`
+	// Config is the part of the .travis.yml for the job, ie. the language,
+	// its version and the os.
+	Config map[string]interface{} `json:"config,omitempty"`
+}
+
+// Error describes an error response from the Travis CI API.
+type Error struct {
+	StatusCode int    `json:"-"`
+	Type       string `json:"error_type,omitempty"`
+	Message    string `json:"error_message,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if len(e.Message) > 0 {
+		return fmt.Sprintf("travis api responded with status %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("travis api responded with status %d", e.StatusCode)
+}
+
+// IsNotFound returns true if the error is a not found response from the
+// Travis CI API.
+func IsNotFound(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e.StatusCode == http.StatusNotFound
+}
+
+// jobsResponse describes a response for the jobs of a build.
+type jobsResponse struct {
+	Jobs []Job `json:"jobs,omitempty"`
+}
+
+// reposResponse describes a response for the repositories of an owner.
+type reposResponse struct {
+	Pagination struct {
+		IsLast bool `json:"is_last,omitempty"`
+	} `json:"@pagination,omitempty"`
+	Repositories []Repository `json:"repositories,omitempty"`
+}
+
+// New returns a client for the Travis CI API at the endpoint, ie.
+// DefaultEndpoint or the API of a Travis CI Enterprise install.
+// If httpClient is nil, http.DefaultClient is used.
+func New(endpoint, token string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if len(endpoint) <= 0 {
+		endpoint = DefaultEndpoint
+	}
+
+	return &Client{
+		Endpoint:   strings.TrimSuffix(endpoint, "/"),
+		Token:      token,
+		httpClient: httpClient,
+	}
+}
+
+// ListRepos lists the active repositories of an owner, ie. a user or an
+// organization.
+func (c *Client) ListRepos(ctx context.Context, owner string) ([]Repository, error) {
+	const limit = 100
+
+	var repos []Repository
+	for offset := 0; ; offset += limit {
+		var r reposResponse
+		path := fmt.Sprintf("/owner/%s/repos?active=true&limit=%d&offset=%d", url.PathEscape(owner), limit, offset)
+		if err := c.get(ctx, path, &r); err != nil {
+			return nil, err
+		}
+
+		repos = append(repos, r.Repositories...)
+		if r.Pagination.IsLast || len(r.Repositories) <= 0 {
+			return repos, nil
+		}
+	}
+}
+
+// GetBranch gets a branch of the repository with the slug, ie. owner/repo,
+// along with its last build and the commit and user it was for.
+func (c *Client) GetBranch(ctx context.Context, slug, branch string) (*Branch, error) {
+	var b Branch
+	path := fmt.Sprintf("/repo/%s/branch/%s?include=build.commit,build.created_by", url.PathEscape(slug), url.PathEscape(branch))
+	if err := c.get(ctx, path, &b); err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+// GetJobs gets the jobs in the build matrix of a build with their config.
+func (c *Client) GetJobs(ctx context.Context, buildID int) ([]Job, error) {
+	var r jobsResponse
+	path := fmt.Sprintf("/build/%d/jobs?include=job.config", buildID)
+	if err := c.get(ctx, path, &r); err != nil {
+		return nil, err
+	}
+
+	return r.Jobs, nil
+}
+
+// get gets the path from the API and decodes the json response into v.
+func (c *Client) get(ctx context.Context, path string, v interface{}) error {
+	// set up the request
+	req, err := http.NewRequestWithContext(ctx, "GET", c.Endpoint+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Travis-API-Version", "3")
+	req.Header.Set("Accept", "application/json")
+	if len(c.Token) > 0 {
+		req.Header.Set("Authorization", "token "+c.Token)
+	}
+
+	// do the request
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// check the status code
+	// it should be 200
+	if resp.StatusCode != http.StatusOK {
+		e := &Error{StatusCode: resp.StatusCode}
+		// The error body is only used for the message, so ignore it if
+		// it is not json.
+		json.NewDecoder(resp.Body).Decode(e)
+		return e
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding json response from %s failed: %v", path, err)
+	}
+
+	return nil
+}
+
+// Name returns a short name for the job from its config, ie. go 1.14/linux.
+// If the config does not describe the job, its number is used.
+func (j Job) Name() string {
+	parts := []string{}
+	if lang, ok := j.Config["language"].(string); ok && len(lang) > 0 {
+		part := lang
+		if version := j.Config[lang]; version != nil {
+			part += " " + fmt.Sprint(version)
+		}
+		parts = append(parts, part)
+	}
+	if os, ok := j.Config["os"].(string); ok && len(os) > 0 {
+		parts = append(parts, os)
+	}
+	if env, ok := j.Config["env"].(string); ok && len(env) > 0 {
+		parts = append(parts, env)
+	}
+
+	if len(parts) <= 0 {
+		return "#" + j.Number
+	}
+	return strings.Join(parts, "/")
+}
+
+// Failed returns true if the job failed or errored.
+func (j Job) Failed() bool {
+	return j.State == "failed" || j.State == "errored"
+}